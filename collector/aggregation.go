@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// histogramAggregate is an histogram computed by the exporter from the
+// observations returned by a request, one per row.
+type histogramAggregate struct {
+	desc         *prometheus.Desc
+	labelsValues []string
+	upperBounds  []float64
+	buckets      map[float64]uint64
+	count        uint64
+	sum          float64
+}
+
+func newHistogramAggregate(desc *prometheus.Desc, upperBounds []float64, labelsValues []string) *histogramAggregate {
+	sorted := append([]float64{}, upperBounds...)
+	sort.Float64s(sorted)
+	// A duplicated bound would count the observations twice
+	sorted = slices.Compact(sorted)
+	buckets := make(map[float64]uint64, len(sorted))
+	for _, upperBound := range sorted {
+		buckets[upperBound] = 0
+	}
+	return &histogramAggregate{
+		desc:         desc,
+		labelsValues: labelsValues,
+		upperBounds:  sorted,
+		buckets:      buckets,
+	}
+}
+
+// checkUpperBounds checks the upper bounds of the buckets of an histogram,
+// the +Inf bucket being added by the exporter.
+func checkUpperBounds(upperBounds []float64) error {
+	for _, upperBound := range upperBounds {
+		if math.IsNaN(upperBound) || math.IsInf(upperBound, 0) {
+			return fmt.Errorf("invalid upper bound %v", upperBound)
+		}
+	}
+	return nil
+}
+
+// observe records weight observations of value.
+func (h *histogramAggregate) observe(value float64, weight uint64) {
+	h.count += weight
+	h.sum += value * float64(weight)
+	i := sort.SearchFloat64s(h.upperBounds, value)
+	for _, upperBound := range h.upperBounds[i:] {
+		h.buckets[upperBound] += weight
+	}
+}
+
+func (h *histogramAggregate) metric() (prometheus.Metric, error) {
+	return prometheus.NewConstHistogram(h.desc, h.count, h.sum, h.buckets, h.labelsValues...)
+}
+
+// histogramAggregates groups the histograms computed by the exporter by name
+// and label values, in the order of their first observation.
+type histogramAggregates struct {
+	byKey      map[string]*histogramAggregate
+	histograms []*histogramAggregate
+}
+
+// get returns the histogram named name with labelsValues, creating it with
+// desc and upperBounds when needed.
+func (a *histogramAggregates) get(name string, desc *prometheus.Desc, upperBounds []float64, labelsValues []string) *histogramAggregate {
+	key := name + "\x00" + strings.Join(labelsValues, "\x00")
+	if h, ok := a.byKey[key]; ok {
+		return h
+	}
+	if a.byKey == nil {
+		a.byKey = make(map[string]*histogramAggregate)
+	}
+	h := newHistogramAggregate(desc, upperBounds, labelsValues)
+	a.byKey[key] = h
+	a.histograms = append(a.histograms, h)
+	return h
+}
+
+// observationWeight returns the number of observations of the row, read from
+// the metricscount column of metric, 1 without such column.
+func observationWeight(row row, metricDefinition Metric, metric string) (uint64, error) {
+	column, ok := metricDefinition.MetricsCount[metric]
+	if !ok {
+		return 1, nil
+	}
+	return row[column].count()
+}
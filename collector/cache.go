@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metricCache holds the results of the last successful scrape of a metric
+// defining a scrape interval.
+type metricCache struct {
+	results     []prometheus.Metric
+	lastScraped time.Time
+}
+
+func metricCacheKey(metric Metric) string {
+	return metric.Context + "\x00" + metric.Request
+}
+
+// metricCacheColumns returns the columns of metric, which tell apart the
+// metrics sharing a context in metric_cache_age_seconds.
+func metricCacheColumns(metric Metric) string {
+	columns := make([]string, 0, len(metric.MetricsDesc))
+	for column := range metric.MetricsDesc {
+		columns = append(columns, strings.ToLower(column))
+	}
+	sort.Strings(columns)
+	return strings.Join(columns, ",")
+}
+
+// scrapeCachedMetric sends the cached results of metric while they are more
+// recent than interval, and scrapes the database to refresh them otherwise.
+func (e *Exporter) scrapeCachedMetric(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric, metric Metric, info databaseInfo,
+	interval time.Duration) error {
+	key := metricCacheKey(metric)
+	e.cacheMu.Lock()
+	cache, ok := e.metricsCache[key]
+	e.cacheMu.Unlock()
+
+	now := time.Now()
+	if ok && now.Sub(cache.lastScraped) < interval {
+		e.logger.Debugw("serving cached metric", "context", metric.Context, "age", now.Sub(cache.lastScraped))
+		for _, m := range cache.results {
+			ch <- m
+		}
+		e.metricCacheAge.WithLabelValues(metric.Context, metricCacheColumns(metric)).Set(now.Sub(cache.lastScraped).Seconds())
+		return nil
+	}
+
+	resultCh := make(chan prometheus.Metric)
+	doneCh := make(chan struct{})
+	var results []prometheus.Metric
+	go func() {
+		for m := range resultCh {
+			results = append(results, m)
+		}
+		close(doneCh)
+	}()
+	err := e.scrapeMetric(ctx, db, resultCh, metric, info)
+	close(resultCh)
+	<-doneCh
+
+	for _, m := range results {
+		ch <- m
+	}
+	if err != nil {
+		return err
+	}
+	e.cacheMu.Lock()
+	e.metricsCache[key] = &metricCache{results: results, lastScraped: now}
+	e.cacheMu.Unlock()
+	e.metricCacheAge.WithLabelValues(metric.Context, metricCacheColumns(metric)).Set(0)
+	return nil
+}
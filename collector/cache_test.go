@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingDatabase answers the requests of cachedMetric, failing them while
+// failing is set, and counts them.
+type countingDatabase struct {
+	mu      sync.Mutex
+	failing bool
+	queries map[string]int
+}
+
+func (d *countingDatabase) query(_ context.Context, query string) (fakeResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries[query]++
+	if d.failing {
+		return fakeResult{}, errors.New("ORA-03113: end-of-file on communication channel")
+	}
+	_, alias, _ := strings.Cut(query, " AS ")
+	return fakeResult{
+		columns: []string{"SEGMENT_NAME", strings.Fields(alias)[0]},
+		types:   []string{"VARCHAR2", "NUMBER"},
+		rows:    [][]driver.Value{{"ORDERS", "1024"}},
+	}, nil
+}
+
+func (d *countingDatabase) count(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.queries[query]
+}
+
+func scrapeCached(t *testing.T, e *Exporter, metric Metric, interval time.Duration) (int, error) {
+	t.Helper()
+	ch := make(chan prometheus.Metric, 10)
+	err := e.scrapeCachedMetric(context.Background(), e.db, ch, metric, databaseInfo{}, interval)
+	return len(ch), err
+}
+
+func cachedMetric(column string) Metric {
+	return Metric{
+		Context:        "size_dba_segments_top100",
+		Labels:         []string{"segment_name"},
+		MetricsDesc:    map[string]string{column: "Size of the segments."},
+		ScrapeInterval: "1h",
+		Request:        "SELECT segment_name, SUM(bytes) AS " + column + " FROM dba_segments GROUP BY segment_name",
+	}
+}
+
+func TestScrapeCachedMetric(t *testing.T) {
+	database := &countingDatabase{queries: make(map[string]int)}
+	e := newFakeExporter(t, &Config{QueryTimeout: 5}, nil, database.query)
+	metric := cachedMetric("bytes")
+
+	for i := 0; i < 2; i++ {
+		sent, err := scrapeCached(t, e, metric, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+	}
+	assert.Equal(t, 1, database.count(metric.Request))
+	age := testutil.ToFloat64(e.metricCacheAge.WithLabelValues(metric.Context, "bytes"))
+	assert.True(t, age > 0 && age < 1)
+
+	// The results are refreshed once the interval has passed
+	sent, err := scrapeCached(t, e, metric, time.Nanosecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 2, database.count(metric.Request))
+	assert.Equal(t, 0.0, testutil.ToFloat64(e.metricCacheAge.WithLabelValues(metric.Context, "bytes")))
+}
+
+func TestScrapeCachedMetricError(t *testing.T) {
+	database := &countingDatabase{queries: make(map[string]int), failing: true}
+	e := newFakeExporter(t, &Config{QueryTimeout: 5}, nil, database.query)
+	metric := cachedMetric("bytes")
+
+	_, err := scrapeCached(t, e, metric, time.Hour)
+	assert.Error(t, err)
+
+	// A failed request is not cached, the next scrape runs it again
+	database.failing = false
+	sent, err := scrapeCached(t, e, metric, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, 2, database.count(metric.Request))
+}
+
+func TestMetricCacheAgeSharedContext(t *testing.T) {
+	database := &countingDatabase{queries: make(map[string]int)}
+	e := newFakeExporter(t, &Config{QueryTimeout: 5}, nil, database.query)
+
+	for _, column := range []string{"table_bytes", "table_partition_bytes", "cluster_bytes"} {
+		_, err := scrapeCached(t, e, cachedMetric(column), time.Hour)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, testutil.CollectAndCount(e.metricCacheAge))
+}
@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"math"
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,9 +35,18 @@ type Exporter struct {
 	duration, error prometheus.Gauge
 	totalScrapes    prometheus.Counter
 	scrapeErrors    *prometheus.CounterVec
+	queryTimeouts   *prometheus.CounterVec
 	scrapeResults   []prometheus.Metric
 	up              prometheus.Gauge
+	metricCacheAge  *prometheus.GaugeVec
 	db              *sql.DB
+	databaseInfo    *databaseInfo
+	// discoveryFailed is the time of the last failed discovery of the
+	// database, retried after discoveryRetryInterval
+	discoveryFailed time.Time
+	metricsHashes   map[int][]byte
+	metricsCache    map[string]*metricCache
+	cacheMu         sync.Mutex
 	logger          *zap.SugaredLogger
 }
 
@@ -47,6 +58,19 @@ type Config struct {
 	CustomMetrics      string
 	QueryTimeout       int
 	DefaultMetricsFile string
+	ConstLabels        map[string]string
+	ScrapeWorkers      int
+	Params             map[string]string
+	SessionInit        []string
+	// Namespace is the first part of the names of the metrics, oracledb when
+	// empty
+	Namespace string
+	// IdentityLabels adds the dbid and db_unique_name labels of the database
+	// to the series of the metrics
+	IdentityLabels bool
+	// AllowTargetDSN lets the targets of /scrape and /probe be connection
+	// strings rather than host:port[/service]
+	AllowTargetDSN bool
 }
 
 // CreateDefaultConfig returns the default configuration of the Exporter
@@ -71,75 +95,48 @@ type Metric struct {
 	FieldToAppend    string
 	Request          string
 	IgnoreZeroResult bool
+	ScrapeInterval   string
+	QueryTimeout     string
+	SerialGroup      string
+	Params           map[string]string
+	MinVersion       string
+	MaxVersion       string
+	DatabaseRole     []string `toml:"database_role" json:"database_role"`
+	OpenMode         []string `toml:"open_mode" json:"open_mode"`
+	PerContainer     bool
+	ContainerInclude []string
+	ContainerExclude []string
+	PerInstance      bool
+	NullValue        string
+	NullValues       map[string]string
+	ValueMap         map[string]float64
+	MetricsQuantiles map[string]map[string]string
+	MetricsCount     map[string]string
+	MetricsSum       map[string]string
+	HistogramBuckets map[string][]float64
+	Relabel          []Relabel
+	MetricsName      map[string]string
+	ConstLabels      map[string]string
+	Namespace        string
 }
 
 // Metrics is a container structure for prometheus metrics
 type Metrics struct {
-	Metric []Metric `json:"metrics"`
+	// Namespace replaces the namespace of the exporter in the names of the
+	// metrics of the file, and Prefix is prepended to their context
+	Namespace string
+	Prefix    string
+	Metric    []Metric `json:"metrics"`
 }
 
 var (
-	additionalMetrics Metrics
-	hashMap           = make(map[int][]byte)
-	namespace         = "oracledb"
-	exporterName      = "exporter"
-)
-
-func timeToSeconds(timeStr string) (float64, error) {
-	// 定义正则表达式
-	pattern := `^([+-])(\d+)\s(\d{2}):(\d{2}):(\d{2})$`
-	re := regexp.MustCompile(pattern)
-
-	// 检查是否匹配
-	if !re.MatchString(timeStr) {
-		return 0, fmt.Errorf("时间格式不匹配，应为 '+DD HH:MM:SS'")
-	}
+	defaultNamespace = "oracledb"
+	exporterName     = "exporter"
 
-	// 提取各个部分
-	matches := re.FindStringSubmatch(timeStr)
-	if len(matches) != 6 {
-		return 0, fmt.Errorf("解析时间失败")
-	}
-
-	sign := matches[1]    // "+" 或 "-"
-	days := matches[2]    // 天数
-	hours := matches[3]   // 小时
-	minutes := matches[4] // 分钟
-	seconds := matches[5] // 秒
-
-	// 转换为数字
-	d, err := strconv.ParseFloat(days, 64)
-	if err != nil {
-		return 0, err
-	}
-	h, err := strconv.Atoi(hours)
-	if err != nil {
-		return 0, err
-	}
-	m, err := strconv.Atoi(minutes)
-	if err != nil {
-		return 0, err
-	}
-	s, err := strconv.Atoi(seconds)
-	if err != nil {
-		return 0, err
-	}
+	errQueryTimeout = errors.New("oracle query timed out")
 
-	// 验证时间范围
-	if h > 23 || m > 59 || s > 59 {
-		return 0, fmt.Errorf("时间值超出范围")
-	}
-
-	// 计算总秒数
-	totalSeconds := d*24*3600 + float64(h)*3600 + float64(m)*60 + float64(s)
-
-	// 应用正负号
-	if sign == "-" {
-		totalSeconds = -totalSeconds
-	}
-
-	return totalSeconds, nil
-}
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
 
 func maskDsn(dsn string) string {
 	parts := strings.Split(dsn, "@")
@@ -152,40 +149,62 @@ func maskDsn(dsn string) string {
 
 // NewExporter creates a new Exporter instance
 func NewExporter(logger *zap.SugaredLogger, cfg *Config) (*Exporter, error) {
+	namespace := cfg.namespace()
 	e := &Exporter{
 		mu:  &sync.Mutex{},
 		dsn: cfg.DSN,
 		duration: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: exporterName,
-			Name:      "last_scrape_duration_seconds",
-			Help:      "Duration of the last scrape of metrics from Oracle DB.",
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "last_scrape_duration_seconds",
+			Help:        "Duration of the last scrape of metrics from Oracle DB.",
+			ConstLabels: cfg.ConstLabels,
 		}),
 		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: exporterName,
-			Name:      "scrapes_total",
-			Help:      "Total number of times Oracle DB was scraped for metrics.",
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "scrapes_total",
+			Help:        "Total number of times Oracle DB was scraped for metrics.",
+			ConstLabels: cfg.ConstLabels,
 		}),
 		scrapeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
-			Namespace: namespace,
-			Subsystem: exporterName,
-			Name:      "scrape_errors_total",
-			Help:      "Total number of times an error occurred scraping a Oracle database.",
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "scrape_errors_total",
+			Help:        "Total number of times an error occurred scraping a Oracle database.",
+			ConstLabels: cfg.ConstLabels,
 		}, []string{"collector"}),
+		queryTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "query_timeouts_total",
+			Help:        "Total number of times a query timed out while scraping a Oracle database.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"collector"}),
+		metricCacheAge: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "metric_cache_age_seconds",
+			Help:        "Age of the cached results served for metrics defining a scrape interval.",
+			ConstLabels: cfg.ConstLabels,
+		}, []string{"collector", "metrics"}),
 		error: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Subsystem: exporterName,
-			Name:      "last_scrape_error",
-			Help:      "Whether the last scrape of metrics from Oracle DB resulted in an error (1 for error, 0 for success).",
+			Namespace:   namespace,
+			Subsystem:   exporterName,
+			Name:        "last_scrape_error",
+			Help:        "Whether the last scrape of metrics from Oracle DB resulted in an error (1 for error, 0 for success).",
+			ConstLabels: cfg.ConstLabels,
 		}),
 		up: prometheus.NewGauge(prometheus.GaugeOpts{
-			Namespace: namespace,
-			Name:      "up",
-			Help:      "Whether the Oracle database server is up.",
+			Namespace:   namespace,
+			Name:        "up",
+			Help:        "Whether the Oracle database server is up.",
+			ConstLabels: cfg.ConstLabels,
 		}),
-		logger: logger,
-		config: cfg,
+		metricsHashes: make(map[int][]byte),
+		metricsCache:  make(map[string]*metricCache),
+		logger:        logger,
+		config:        cfg,
 	}
 	e.metricsToScrape = e.DefaultMetrics()
 	err := e.connect()
@@ -222,6 +241,29 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements prometheus.Collector.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	e.collect(context.Background(), ch)
+}
+
+// WithContext returns a collector running the scrapes of the exporter with
+// ctx, so that their requests are cancelled once ctx is done. The returned
+// collector does not describe any metric, hence it is not checked for
+// consistency by the registry it is registered in.
+func (e *Exporter) WithContext(ctx context.Context) prometheus.Collector {
+	return contextCollector{exporter: e, ctx: ctx}
+}
+
+type contextCollector struct {
+	exporter *Exporter
+	ctx      context.Context
+}
+
+func (c contextCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c contextCollector) Collect(ch chan<- prometheus.Metric) {
+	c.exporter.collect(c.ctx, ch)
+}
+
+func (e *Exporter) collect(ctx context.Context, ch chan<- prometheus.Metric) {
 	// they are running scheduled scrapes we should only scrape new data
 	// on the interval
 	if e.scrapeInterval != nil && *e.scrapeInterval != 0 {
@@ -237,11 +279,13 @@ func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
 	// otherwise do a normal scrape per request
 	e.mu.Lock() // ensure no simultaneous scrapes
 	defer e.mu.Unlock()
-	e.scrape(ch)
+	e.scrape(ctx, ch)
 	ch <- e.duration
 	ch <- e.totalScrapes
 	ch <- e.error
 	e.scrapeErrors.Collect(ch)
+	e.queryTimeouts.Collect(ch)
+	e.metricCacheAge.Collect(ch)
 	ch <- e.up
 }
 
@@ -256,7 +300,7 @@ func (e *Exporter) RunScheduledScrapes(ctx context.Context, si time.Duration) {
 		select {
 		case <-ticker.C:
 			e.mu.Lock() // ensure no simultaneous scrapes
-			e.scheduledScrape()
+			e.scheduledScrape(ctx)
 			e.mu.Unlock()
 		case <-ctx.Done():
 			return
@@ -264,7 +308,7 @@ func (e *Exporter) RunScheduledScrapes(ctx context.Context, si time.Duration) {
 	}
 }
 
-func (e *Exporter) scheduledScrape() {
+func (e *Exporter) scheduledScrape(ctx context.Context) {
 	metricCh := make(chan prometheus.Metric, 5)
 
 	wg := &sync.WaitGroup{}
@@ -281,20 +325,22 @@ func (e *Exporter) scheduledScrape() {
 			return
 		}
 	}()
-	e.scrape(metricCh)
+	e.scrape(ctx, metricCh)
 
 	// report metadata metrics
 	metricCh <- e.duration
 	metricCh <- e.totalScrapes
 	metricCh <- e.error
 	e.scrapeErrors.Collect(metricCh)
+	e.queryTimeouts.Collect(metricCh)
+	e.metricCacheAge.Collect(metricCh)
 	metricCh <- e.up
 
 	close(metricCh)
 	wg.Wait()
 }
 
-func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
+func (e *Exporter) scrape(ctx context.Context, ch chan<- prometheus.Metric) {
 	e.totalScrapes.Inc()
 	var err error
 	var errmutex sync.Mutex
@@ -308,7 +354,7 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 		}
 	}(time.Now())
 
-	if err = e.db.Ping(); err != nil {
+	if err = e.db.PingContext(ctx); err != nil {
 		if strings.Contains(err.Error(), "sql: database is closed") {
 			e.logger.Info("Reconnecting to DB")
 			err = e.connect()
@@ -318,7 +364,7 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 		}
 	}
 
-	if err = e.db.Ping(); err != nil {
+	if err = e.db.PingContext(ctx); err != nil {
 		e.logger.Errorw("error pinging oracle:", err.Error())
 		e.up.Set(0)
 		return
@@ -331,24 +377,101 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 		e.reloadMetrics()
 	}
 
+	if e.databaseInfo == nil && e.needsDiscovery() && time.Since(e.discoveryFailed) >= discoveryRetryInterval {
+		info, err1 := e.discoverDatabase(ctx)
+		if err1 != nil {
+			e.logger.Debugw("Unable to discover the database, metrics with conditions are skipped", "error", err1)
+			e.discoveryFailed = time.Now()
+		} else {
+			e.logger.Debugw("Discovered database", "version", info.version, "cluster", info.cluster)
+			e.databaseInfo = info
+		}
+	}
+
+	info := databaseInfo{}
+	if e.databaseInfo != nil {
+		info = *e.databaseInfo
+	}
+	if e.needsDatabaseState() {
+		if err1 := e.readDatabaseState(ctx, &info); err1 != nil {
+			e.logger.Warnw("Metrics depending on the database role or open mode are skipped", "error", err1)
+		}
+	}
+	if e.needsInstances() {
+		if err1 := e.readInstances(ctx, &info); err1 != nil {
+			e.logger.Debugw("Unable to list the instances, metrics are scraped on the current instance only", "error", err1)
+		}
+	}
+	if e.needsContainers() {
+		if err1 := e.readContainers(ctx, &info); err1 != nil {
+			e.logger.Debugw("Unable to list the pluggable databases, metrics are scraped in the current container only", "error", err1)
+		}
+	}
+
 	wg := sync.WaitGroup{}
 
+	// workers bounds the number of requests running at the same time, and
+	// the requests of a serial group never run concurrently
+	var workers chan struct{}
+	if e.config.ScrapeWorkers > 0 {
+		workers = make(chan struct{}, e.config.ScrapeWorkers)
+	}
+	serialGroups := make(map[string]*sync.Mutex)
+	for _, metric := range e.metricsToScrape.Metric {
+		if metric.SerialGroup != "" && serialGroups[metric.SerialGroup] == nil {
+			serialGroups[metric.SerialGroup] = &sync.Mutex{}
+		}
+	}
+
 	for _, metric := range e.metricsToScrape.Metric {
+		if dependsOnDatabaseState(metric) && info.role == "" {
+			// The state could not be read, the metric is missing rather than
+			// not applicable
+			e.logger.Warnw("Skipping metric as the database role and open mode are unknown", "context", metric.Context)
+			e.scrapeErrors.WithLabelValues(metric.Context).Inc()
+			continue
+		}
+		if !e.isMetricEnabled(metric, info) {
+			e.logger.Debugw("Skipping metric not applicable to the database", "context", metric.Context)
+			continue
+		}
 		wg.Add(1)
 		metric := metric //https://golang.org/doc/faq#closures_and_goroutines
 
 		f := func() {
 			defer wg.Done()
 
+			// Lock the group before taking a worker, so that a request waiting
+			// for its group does not hold a worker
+			if group, ok := serialGroups[metric.SerialGroup]; ok {
+				group.Lock()
+				defer group.Unlock()
+			}
+			if workers != nil {
+				workers <- struct{}{}
+				defer func() { <-workers }()
+			}
+
 			e.logger.Debugw("About to scrape metric: ")
 			e.logger.Debugw("- Metric MetricsDesc: ", fmt.Sprintf("%+v", metric.MetricsDesc))
 			e.logger.Debugw("- Metric Context: ", metric.Context)
 			e.logger.Debugw("- Metric MetricsType: ", fmt.Sprintf("%+v", metric.MetricsType))
 			e.logger.Debugw("- Metric MetricsBuckets: ", fmt.Sprintf("%+v", metric.MetricsBuckets), "(Ignored unless Histogram type)")
+			e.logger.Debugw("- Metric HistogramBuckets: ", fmt.Sprintf("%+v", metric.HistogramBuckets), "(Ignored unless Histogram type)")
 			e.logger.Debugw("- Metric Labels: ", fmt.Sprintf("%+v", metric.Labels))
 			e.logger.Debugw("- Metric FieldToAppend: ", metric.FieldToAppend)
 			e.logger.Debugw("- Metric IgnoreZeroResult: ", fmt.Sprintf("%+v", metric.IgnoreZeroResult))
+			e.logger.Debugw("- Metric ScrapeInterval: ", metric.ScrapeInterval)
+			e.logger.Debugw("- Metric QueryTimeout: ", metric.QueryTimeout)
+			e.logger.Debugw("- Metric SerialGroup: ", metric.SerialGroup)
 			e.logger.Debugw("- Metric Request: ", metric.Request)
+			e.logger.Debugw("- Metric Params: ", fmt.Sprintf("%+v", metric.Params))
+			e.logger.Debugw("- Metric NullValue: ", metric.NullValue, "NullValues", fmt.Sprintf("%+v", metric.NullValues))
+			e.logger.Debugw("- Metric ValueMap: ", fmt.Sprintf("%+v", metric.ValueMap))
+			e.logger.Debugw("- Metric Relabel: ", fmt.Sprintf("%+v", metric.Relabel))
+			e.logger.Debugw("- Metric MetricsName: ", fmt.Sprintf("%+v", metric.MetricsName))
+			e.logger.Debugw("- Metric ConstLabels: ", fmt.Sprintf("%+v", metric.ConstLabels))
+			e.logger.Debugw("- Metric Namespace: ", metric.Namespace)
 
 			if len(metric.Request) == 0 {
 				e.logger.Errorw("Error scraping for ", metric.MetricsDesc, ". Did you forget to define request in your metrics config file?")
@@ -360,18 +483,50 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 				return
 			}
 
+			for column := range metric.MetricsDesc {
+				if _, err1 := nullPolicy(metric, column); err1 != nil {
+					e.logger.Errorw("Invalid nullvalue configuration of metric", "context", metric.Context, "error", err1)
+					return
+				}
+				if _, err1 := buildMetricName(e.metricNamespace(metric), metric, column, column); err1 != nil && metric.FieldToAppend == "" {
+					e.logger.Errorw("Invalid metric name of metric", "context", metric.Context, "error", err1)
+					return
+				}
+			}
+
+			if err1 := e.checkMetricLabels(metric); err1 != nil {
+				e.logger.Errorw("Invalid labels configuration of metric", "context", metric.Context, "error", err1)
+				return
+			}
+
 			for column, metricType := range metric.MetricsType {
 				if metricType == "histogram" {
 					_, ok := metric.MetricsBuckets[column]
-					if !ok {
+					if _, aggregated := metric.HistogramBuckets[column]; !ok && !aggregated {
 						e.logger.Errorw("Unable to find MetricsBuckets configuration key for metric. (metric=" + column + ")")
 						return
 					}
 				}
 			}
 
+			var interval time.Duration
+			if metric.ScrapeInterval != "" {
+				var err1 error
+				interval, err1 = time.ParseDuration(metric.ScrapeInterval)
+				if err1 != nil {
+					e.logger.Errorw("Unable to parse scrapeinterval of metric", "context", metric.Context, "scrapeinterval", metric.ScrapeInterval, "error", err1)
+					return
+				}
+			}
+
 			scrapeStart := time.Now()
-			if err1 := e.ScrapeMetric(e.db, ch, metric); err1 != nil {
+			var err1 error
+			if interval > 0 {
+				err1 = e.scrapeCachedMetric(ctx, e.db, ch, metric, info, interval)
+			} else {
+				err1 = e.scrapeMetric(ctx, e.db, ch, metric, info)
+			}
+			if err1 != nil {
 				errmutex.Lock()
 				{
 					err = err1
@@ -379,6 +534,9 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 				errmutex.Unlock()
 				e.logger.Errorw("scrapeMetricContext", metric.Context, "ScrapeDuration", time.Since(scrapeStart), "msg", err1.Error())
 				e.scrapeErrors.WithLabelValues(metric.Context).Inc()
+				if errors.Is(err1, errQueryTimeout) {
+					e.queryTimeouts.WithLabelValues(metric.Context).Inc()
+				}
 			} else {
 				e.logger.Debugw("successfully scraped metric: ", metric.Context, metric.MetricsDesc, time.Since(scrapeStart))
 			}
@@ -391,11 +549,11 @@ func (e *Exporter) scrape(ch chan<- prometheus.Metric) {
 func (e *Exporter) connect() error {
 	_, err := url.Parse(e.dsn)
 	if err != nil {
-		e.logger.Errorw("malformed DSN: ", maskDsn(e.dsn))
+		e.logger.Errorw("malformed DSN", "dsn", maskDsn(e.dsn))
 		return err
 	}
 	e.logger.Debugw("launching connection: ", maskDsn(e.dsn))
-	db, err := sql.Open("oracle", e.dsn)
+	db, err := openDB(e.dsn, e.config.SessionInit)
 	if err != nil {
 		e.logger.Errorw("error while connecting to", e.dsn)
 		return err
@@ -406,9 +564,22 @@ func (e *Exporter) connect() error {
 	db.SetMaxOpenConns(e.config.MaxOpenConns)
 	e.logger.Debugw("successfully connected to: ", maskDsn(e.dsn))
 	e.db = db
+	e.databaseInfo = nil
+	e.discoveryFailed = time.Time{}
 	return nil
 }
 
+// Close closes the connection pool of the exporter, waiting for a running
+// scrape to complete.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
+
 func (e *Exporter) checkIfMetricsChanged() bool {
 	for i, _customMetrics := range strings.Split(e.config.CustomMetrics, ",") {
 		if len(_customMetrics) == 0 {
@@ -421,9 +592,9 @@ func (e *Exporter) checkIfMetricsChanged() bool {
 			return false
 		}
 		// If any of files has been changed reload metrics
-		if !bytes.Equal(hashMap[i], h.Sum(nil)) {
+		if !bytes.Equal(e.metricsHashes[i], h.Sum(nil)) {
 			e.logger.Infow(_customMetrics, "has been changed. Reloading metrics...")
-			hashMap[i] = h.Sum(nil)
+			e.metricsHashes[i] = h.Sum(nil)
 			return true
 		}
 	}
@@ -443,8 +614,12 @@ func hashFile(h hash.Hash, fn string) error {
 }
 
 func (e *Exporter) reloadMetrics() {
-	// Truncate metricsToScrape
+	// Truncate metricsToScrape and the results cached for the previous definitions
 	e.metricsToScrape.Metric = []Metric{}
+	e.cacheMu.Lock()
+	e.metricsCache = make(map[string]*metricCache)
+	e.cacheMu.Unlock()
+	e.metricCacheAge.Reset()
 
 	// Load default metrics
 	defaultMetrics := e.DefaultMetrics()
@@ -453,6 +628,7 @@ func (e *Exporter) reloadMetrics() {
 	// If custom metrics, load it
 	if strings.Compare(e.config.CustomMetrics, "") != 0 {
 		for _, _customMetrics := range strings.Split(e.config.CustomMetrics, ",") {
+			var additionalMetrics Metrics
 			if strings.HasSuffix(_customMetrics, "toml") {
 				if err := loadTomlMetricsConfig(_customMetrics, &additionalMetrics); err != nil {
 					panic(err)
@@ -479,6 +655,9 @@ func loadYamlMetricsConfig(_metricsFileName string, metrics *Metrics) error {
 	if err := yaml.Unmarshal(yamlBytes, metrics); err != nil {
 		return fmt.Errorf("cannot unmarshal the metrics config %s: %w", _metricsFileName, err)
 	}
+	if err := metrics.applyFileSettings(); err != nil {
+		return fmt.Errorf("invalid metrics config %s: %w", _metricsFileName, err)
+	}
 	return nil
 }
 
@@ -486,151 +665,476 @@ func loadTomlMetricsConfig(_customMetrics string, metrics *Metrics) error {
 	if _, err := toml.DecodeFile(_customMetrics, metrics); err != nil {
 		return fmt.Errorf("cannot read the metrics config %s: %w", _customMetrics, err)
 	}
+	if err := metrics.applyFileSettings(); err != nil {
+		return fmt.Errorf("invalid metrics config %s: %w", _customMetrics, err)
+	}
+	return nil
+}
+
+// applyFileSettings applies the namespace and the prefix of a metrics file to
+// its metrics.
+func (m *Metrics) applyFileSettings() error {
+	if m.Namespace != "" {
+		if err := ValidateNamespace(m.Namespace); err != nil {
+			return err
+		}
+	}
+	if m.Prefix != "" && !metricNamePattern.MatchString(m.Prefix) {
+		return fmt.Errorf("invalid metrics prefix %q", m.Prefix)
+	}
+	for i := range m.Metric {
+		if _, err := compileRelabel(m.Metric[i].Relabel); err != nil {
+			return fmt.Errorf("metric %s: %w", m.Metric[i].Context, err)
+		}
+		if m.Metric[i].Namespace != "" {
+			if err := ValidateNamespace(m.Metric[i].Namespace); err != nil {
+				return fmt.Errorf("metric %s: %w", m.Metric[i].Context, err)
+			}
+		}
+		if m.Metric[i].Namespace == "" {
+			m.Metric[i].Namespace = m.Namespace
+		}
+		if m.Prefix != "" {
+			m.Metric[i].Context = m.Prefix + "_" + m.Metric[i].Context
+		}
+	}
+	return nil
+}
+
+// ValidateNamespace checks that namespace can start the name of a metric.
+func ValidateNamespace(namespace string) error {
+	if !metricNamePattern.MatchString(namespace) {
+		return fmt.Errorf("invalid metrics namespace %q", namespace)
+	}
 	return nil
 }
 
+// namespace returns the namespace of the metrics of the exporter.
+func (c *Config) namespace() string {
+	if c.Namespace == "" {
+		return defaultNamespace
+	}
+	return c.Namespace
+}
+
+// metricNamespace returns the namespace of a metric, the one of the exporter
+// unless the metric or its file defines one.
+func (e *Exporter) metricNamespace(metricDefinition Metric) string {
+	if metricDefinition.Namespace != "" {
+		return metricDefinition.Namespace
+	}
+	return e.config.namespace()
+}
+
 // ScrapeMetric is an interface method to call scrapeGenericValues using Metric struct values
 func (e *Exporter) ScrapeMetric(db *sql.DB, ch chan<- prometheus.Metric, metricDefinition Metric) error {
-	e.logger.Debugw("calling function ScrapeGenericValues()")
-	return e.scrapeGenericValues(db, ch, metricDefinition.Context, metricDefinition.Labels,
-		metricDefinition.MetricsDesc, metricDefinition.MetricsType, metricDefinition.MetricsBuckets,
-		metricDefinition.FieldToAppend, metricDefinition.IgnoreZeroResult,
-		metricDefinition.Request)
+	return e.scrapeMetric(context.Background(), db, ch, metricDefinition, databaseInfo{})
+}
+
+// query describes how the request of a metric is run.
+type query struct {
+	request     string
+	args        []interface{}
+	timeout     time.Duration
+	constLabels prometheus.Labels
+}
+
+// queryer runs requests, it is implemented by *sql.DB and *sql.Conn.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (e *Exporter) scrapeMetric(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric, metricDefinition Metric, info databaseInfo) error {
+	queryTimeout, err := e.queryTimeout(metricDefinition)
+	if err != nil {
+		return err
+	}
+	args, err := e.bindArgs(metricDefinition)
+	if err != nil {
+		return err
+	}
+	q := query{
+		request:     metricDefinition.Request,
+		args:        args,
+		timeout:     queryTimeout,
+		constLabels: e.constLabels(metricDefinition, info),
+	}
+	queries := []query{q}
+	if metricDefinition.PerInstance && len(info.instances) > 0 {
+		queries = instanceQueries(q, info)
+	}
+
+	var errs []error
+	for _, q := range queries {
+		if metricDefinition.PerContainer && len(info.containers) > 0 {
+			err = e.scrapeContainers(ctx, db, ch, metricDefinition, q, info.containers)
+		} else {
+			e.logger.Debugw("calling function ScrapeGenericValues()")
+			err = e.scrapeGenericValues(ctx, db, ch, metricDefinition, q)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// constLabels returns the constant labels of the series of a metric: the ones
+// of the exporter, overridden by the ones of the metric, and the identity of
+// the database when enabled.
+func (e *Exporter) constLabels(metricDefinition Metric, info databaseInfo) prometheus.Labels {
+	constLabels := mergeMaps(e.config.ConstLabels, metricDefinition.ConstLabels)
+	if e.config.IdentityLabels {
+		if _, ok := constLabels["dbid"]; !ok && info.dbid != "" {
+			constLabels["dbid"] = info.dbid
+		}
+		if _, ok := constLabels["db_unique_name"]; !ok && info.dbUniqueName != "" {
+			constLabels["db_unique_name"] = info.dbUniqueName
+		}
+	}
+	return constLabels
+}
+
+// checkMetricLabels checks the labels of a metric against the constant labels
+// added to its series, as a label defined twice makes the descriptions of the
+// series invalid.
+func (e *Exporter) checkMetricLabels(metricDefinition Metric) error {
+	// added maps the constant labels of the series to the setting adding them
+	added := make(map[string]string)
+	for name := range e.config.ConstLabels {
+		added[name] = "the constant labels of the exporter"
+	}
+	if e.config.IdentityLabels {
+		added["dbid"] = "the identity labels"
+		added["db_unique_name"] = "the identity labels"
+	}
+	for name := range metricDefinition.ConstLabels {
+		if err := checkConstLabelName(name); err != nil {
+			return fmt.Errorf("constlabels: %w", err)
+		}
+		added[name] = "constlabels"
+	}
+	if metricDefinition.PerInstance {
+		added["inst_id"] = "perinstance"
+		added["instance_name"] = "perinstance"
+	}
+	if metricDefinition.PerContainer {
+		added["con_name"] = "percontainer"
+	}
+	for _, label := range metricDefinition.Labels {
+		if setting, ok := added[label]; ok {
+			return fmt.Errorf("label %s is also added by %s", label, setting)
+		}
+	}
+	for _, r := range metricDefinition.Relabel {
+		action := strings.ToLower(r.Action)
+		if action == "drop" || action == "keep" {
+			continue
+		}
+		target := r.TargetLabel
+		if target == "" {
+			target = r.SourceLabel
+		}
+		if setting, ok := added[target]; ok {
+			return fmt.Errorf("relabel targetlabel %s is also added by %s", target, setting)
+		}
+	}
+	return nil
+}
+
+// checkLabelNames checks that the variable labels of a series are valid and
+// distinct from its constant labels, which prometheus.NewDesc requires.
+func checkLabelNames(labels []string, constLabels prometheus.Labels) error {
+	seen := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		if !validLabelName(label) {
+			return fmt.Errorf("invalid label name %q", label)
+		}
+		if _, ok := constLabels[label]; ok {
+			return fmt.Errorf("label %s is also a constant label", label)
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate label %s", label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+// queryTimeout returns the timeout of the metric request, which defaults to
+// the query timeout of the exporter.
+func (e *Exporter) queryTimeout(metricDefinition Metric) (time.Duration, error) {
+	if metricDefinition.QueryTimeout == "" {
+		return time.Duration(e.config.QueryTimeout) * time.Second, nil
+	}
+	queryTimeout, err := time.ParseDuration(metricDefinition.QueryTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid querytimeout %q: %w", metricDefinition.QueryTimeout, err)
+	}
+	return queryTimeout, nil
 }
 
 // generic method for retrieving metrics.
-func (e *Exporter) scrapeGenericValues(db *sql.DB, ch chan<- prometheus.Metric, context string, labels []string,
-	metricsDesc map[string]string, metricsType map[string]string, metricsBuckets map[string]map[string]string, fieldToAppend string, ignoreZeroResult bool, request string) error {
+func (e *Exporter) scrapeGenericValues(ctx context.Context, db queryer, ch chan<- prometheus.Metric, metricDefinition Metric, q query) error {
+	context := metricDefinition.Context
+	metricsDesc := metricDefinition.MetricsDesc
+	metricsType := metricDefinition.MetricsType
+	fieldToAppend := metricDefinition.FieldToAppend
 	metricsCount := 0
-	genericParser := func(row map[string]string) error {
+	var histograms histogramAggregates
+	relabelRules, err := compileRelabel(metricDefinition.Relabel)
+	if err != nil {
+		return err
+	}
+	for column, upperBounds := range metricDefinition.HistogramBuckets {
+		if err := checkUpperBounds(upperBounds); err != nil {
+			return fmt.Errorf("histogrambuckets of %s: %w", column, err)
+		}
+	}
+	genericParser := func(row row) error {
 		// Construct labels value
 		labelsValues := []string{}
-		for _, label := range labels {
-			labelsValues = append(labelsValues, row[label])
+		for _, label := range metricDefinition.Labels {
+			labelsValues = append(labelsValues, row[label].String())
+		}
+		labels, relabelledValues, keep := relabel(relabelRules, metricDefinition.Labels, labelsValues)
+		if !keep {
+			e.logger.Debugw("Dropping series", "context", context, "labels", labelsValues)
+			return nil
+		}
+		labelsValues = relabelledValues
+		if err := checkLabelNames(labels, q.constLabels); err != nil {
+			return fmt.Errorf("invalid labels of metric %s: %w", context, err)
 		}
-		var value float64
-		var err error
 		// Construct Prometheus values to sent back
 		for metric, metricHelp := range metricsDesc {
-			value, err = strconv.ParseFloat(strings.TrimSpace(row[metric]), 64)
-			// If not a float, skip current metric
+			metricType := strings.ToLower(metricsType[strings.ToLower(metric)])
+			// Histograms with histogrambuckets are computed by the exporter,
+			// the column of the metric holding an observation
+			aggregated := metricType == "histogram" && len(metricDefinition.HistogramBuckets[metric]) > 0
+			var value float64
+			var err error
+			switch {
+			case metricType == "info":
+				// Info metrics carry their facts in labels, the column of the
+				// metric does not need to exist
+				value = 1
+			case (metricType == "summary" || metricType == "histogram") && !aggregated:
+				// The values of summaries and histograms are read by
+				// summaryValues and histogramValues
+			default:
+				value, err = row[metric].mapValue(metricDefinition.ValueMap).float()
+				if row[metric].null {
+					var ok bool
+					value, ok, err = nullValue(metricDefinition, metric)
+					if err == nil && !ok {
+						e.logger.Debugw("Skipping NULL value", "context", context, "metric", metric)
+						continue
+					}
+				}
+			}
+			// If not a number, skip current metric
+			if err != nil {
+				e.logger.Errorw("Unable to convert current value to float", "metric", metric, "metricHelp", metricHelp, "error", err)
+				continue
+			}
+			e.logger.Debugw("Query result looks like: ", value)
+
+			column := metric
+			// If metric uses a field content in metric's name
+			if fieldToAppend != "" {
+				column = appendedName(row, fieldToAppend)
+			}
+			metricName, err := buildMetricName(e.metricNamespace(metricDefinition), metricDefinition, metric, column)
 			if err != nil {
-				// Try parse string as format "+00 00:00:00"
-				value, err = timeToSeconds(strings.TrimSpace(row[metric]))
+				e.logger.Errorw("Invalid metric name", "metric", metric, "metricHelp", metricHelp, "error", err)
+				continue
+			}
+			if metricType == "info" {
+				metricName = infoName(metricName)
+			}
+			desc := prometheus.NewDesc(
+				metricName,
+				metricHelp,
+				labels, q.constLabels,
+			)
+			switch {
+			case aggregated:
+				weight, err := observationWeight(row, metricDefinition, metric)
 				if err != nil {
-					e.logger.Errorw("msg", "Unable to convert current value to float", "metric", metric, "metricHelp", metricHelp, "value", row[metric])
+					e.logger.Errorw("Unable to read the number of observations", "metric", metric, "metricHelp", metricHelp, "error", err)
 					continue
 				}
-			}
-			e.logger.Debugw("Query result looks like: ", value)
-			// If metric do not use a field content in metric's name
-			if strings.Compare(fieldToAppend, "") == 0 {
-				desc := prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, context, metric),
-					metricHelp,
-					labels, nil,
-				)
-				if metricsType[strings.ToLower(metric)] == "histogram" {
-					count, err := strconv.ParseUint(strings.TrimSpace(row["count"]), 10, 64)
-					if err != nil {
-						e.logger.Errorw("Unable to convert count value to int (metric=" + metric +
-							",metricHelp=" + metricHelp + ",value=<" + row["count"] + ">)")
-						continue
-					}
-					buckets := make(map[float64]uint64)
-					for field, le := range metricsBuckets[metric] {
-						lelimit, err := strconv.ParseFloat(strings.TrimSpace(le), 64)
-						if err != nil {
-							e.logger.Errorw("Unable to convert bucket limit value to float (metric=" + metric +
-								",metricHelp=" + metricHelp + ",bucketlimit=<" + le + ">)")
-							continue
-						}
-						counter, err := strconv.ParseUint(strings.TrimSpace(row[field]), 10, 64)
-						if err != nil {
-							e.logger.Errorw("Unable to convert ", field, " value to int (metric="+metric+
-								",metricHelp="+metricHelp+",value=<"+row[field]+">)")
-							continue
-						}
-						buckets[lelimit] = counter
-					}
-					ch <- prometheus.MustNewConstHistogram(desc, count, value, buckets, labelsValues...)
-				} else {
-					ch <- prometheus.MustNewConstMetric(desc, getMetricType(metric, metricsType), value, labelsValues...)
+				histograms.get(metricName, desc, metricDefinition.HistogramBuckets[metric], labelsValues).observe(value, weight)
+			case metricType == "summary":
+				count, sum, quantiles, err := summaryValues(row, metricDefinition, metric)
+				if err != nil {
+					e.logger.Errorw("Unable to read summary values", "metric", metric, "metricHelp", metricHelp, "error", err)
+					continue
 				}
-				// If no labels, use metric name
-			} else {
-				desc := prometheus.NewDesc(
-					prometheus.BuildFQName(namespace, context, cleanName(row[fieldToAppend])),
-					metricHelp,
-					nil, nil,
-				)
-				if metricsType[strings.ToLower(metric)] == "histogram" {
-					count, err := strconv.ParseUint(strings.TrimSpace(row["count"]), 10, 64)
-					if err != nil {
-						e.logger.Errorw("Unable to convert count value to int (metric=" + metric +
-							",metricHelp=" + metricHelp + ",value=<" + row["count"] + ">)")
-						continue
-					}
-					buckets := make(map[float64]uint64)
-					for field, le := range metricsBuckets[metric] {
-						lelimit, err := strconv.ParseFloat(strings.TrimSpace(le), 64)
-						if err != nil {
-							e.logger.Errorw("Unable to convert bucket limit value to float (metric=" + metric +
-								",metricHelp=" + metricHelp + ",bucketlimit=<" + le + ">)")
-							continue
-						}
-						counter, err := strconv.ParseUint(strings.TrimSpace(row[field]), 10, 64)
-						if err != nil {
-							e.logger.Errorw("Unable to convert ", field, " value to int (metric="+metric+
-								",metricHelp="+metricHelp+",value=<"+row[field]+">)")
-							continue
-						}
-						buckets[lelimit] = counter
-					}
-					ch <- prometheus.MustNewConstHistogram(desc, count, value, buckets)
-				} else {
-					ch <- prometheus.MustNewConstMetric(desc, getMetricType(metric, metricsType), value)
+				m, err := prometheus.NewConstSummary(desc, count, sum, quantiles, labelsValues...)
+				if err != nil {
+					return err
+				}
+				ch <- m
+			case metricType == "histogram":
+				count, sum, buckets, err := histogramValues(row, metricDefinition, metric)
+				if err != nil {
+					e.logger.Errorw("Unable to read histogram values", "metric", metric, "metricHelp", metricHelp, "error", err)
+					continue
+				}
+				m, err := prometheus.NewConstHistogram(desc, count, sum, buckets, labelsValues...)
+				if err != nil {
+					return err
 				}
+				ch <- m
+			default:
+				m, err := prometheus.NewConstMetric(desc, getMetricType(metric, metricsType), value, labelsValues...)
+				if err != nil {
+					return err
+				}
+				ch <- m
 			}
 			metricsCount++
 		}
 		return nil
 	}
 	e.logger.Debugw("Calling function GeneratePrometheusMetrics()")
-	err := e.generatePrometheusMetrics(db, genericParser, request)
+	err = e.generatePrometheusMetrics(ctx, db, genericParser, q.request, q.args, q.timeout)
 	e.logger.Debugw("ScrapeGenericValues() - metricsCount: ", metricsCount)
 	if err != nil {
 		return err
 	}
-	if !ignoreZeroResult && metricsCount == 0 {
+	for _, h := range histograms.histograms {
+		m, err := h.metric()
+		if err != nil {
+			return err
+		}
+		ch <- m
+	}
+	if !metricDefinition.IgnoreZeroResult && metricsCount == 0 {
 		return errors.New("No metrics found while parsing")
 	}
 	return err
 }
 
+// countAndSum reads the count and the sum of the observations of a summary or
+// an histogram from row. The count is read from the column given by
+// metricscount, "count" by default, and the sum from the column given by
+// metricssum, the column of the metric by default.
+func countAndSum(row row, metricDefinition Metric, metric string) (uint64, float64, error) {
+	countColumn, ok := metricDefinition.MetricsCount[metric]
+	if !ok {
+		countColumn = "count"
+	}
+	count, err := row[countColumn].count()
+	if err != nil {
+		return 0, 0, fmt.Errorf("count column %s: %w", countColumn, err)
+	}
+	sumColumn, ok := metricDefinition.MetricsSum[metric]
+	if !ok {
+		sumColumn = metric
+	}
+	sum, err := row[sumColumn].float()
+	if err != nil {
+		return 0, 0, fmt.Errorf("sum column %s: %w", sumColumn, err)
+	}
+	return count, sum, nil
+}
+
+// summaryValues reads the summary of metric from row, the quantiles being
+// read from the columns of metricsquantiles.
+func summaryValues(row row, metricDefinition Metric, metric string) (uint64, float64, map[float64]float64, error) {
+	count, sum, err := countAndSum(row, metricDefinition, metric)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	quantiles := make(map[float64]float64, len(metricDefinition.MetricsQuantiles[metric]))
+	for column, q := range metricDefinition.MetricsQuantiles[metric] {
+		quantile, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+		if err != nil || quantile < 0 || quantile > 1 {
+			return 0, 0, nil, fmt.Errorf("invalid quantile %q of column %s", q, column)
+		}
+		// A NULL quantile, such as the percentile of no observation, is
+		// not known
+		value := math.NaN()
+		if !row[column].null {
+			if value, err = row[column].float(); err != nil {
+				return 0, 0, nil, fmt.Errorf("quantile column %s: %w", column, err)
+			}
+		}
+		quantiles[quantile] = value
+	}
+	return count, sum, quantiles, nil
+}
+
+// histogramValues reads the histogram of metric from row, the cumulative
+// counts of the buckets being read from the columns of metricsbuckets. The
+// counts must not decrease as the upper bounds increase, nor exceed the count
+// of the histogram.
+func histogramValues(row row, metricDefinition Metric, metric string) (uint64, float64, map[float64]uint64, error) {
+	count, sum, err := countAndSum(row, metricDefinition, metric)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	buckets := make(map[float64]uint64, len(metricDefinition.MetricsBuckets[metric]))
+	for column, le := range metricDefinition.MetricsBuckets[metric] {
+		upperBound, err := strconv.ParseFloat(strings.TrimSpace(le), 64)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid bucket upper bound %q of column %s", le, column)
+		}
+		bucketCount, err := row[column].count()
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("bucket column %s: %w", column, err)
+		}
+		buckets[upperBound] = bucketCount
+	}
+
+	upperBounds := make([]float64, 0, len(buckets))
+	for upperBound := range buckets {
+		upperBounds = append(upperBounds, upperBound)
+	}
+	sort.Float64s(upperBounds)
+	var previous uint64
+	for _, upperBound := range upperBounds {
+		if buckets[upperBound] < previous {
+			return 0, 0, nil, fmt.Errorf("bucket %v counts %d observations, less than the previous bucket, buckets must be cumulative",
+				upperBound, buckets[upperBound])
+		}
+		previous = buckets[upperBound]
+	}
+	if previous > count {
+		return 0, 0, nil, fmt.Errorf("buckets count %d observations, more than the count %d of the histogram", previous, count)
+	}
+	return count, sum, buckets, nil
+}
+
 // inspired by https://kylewbanks.com/blog/query-result-to-map-in-golang
 // Parse SQL result and call parsing function to each row
-func (e *Exporter) generatePrometheusMetrics(db *sql.DB, parse func(row map[string]string) error, query string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(e.config.QueryTimeout)*time.Second)
+func (e *Exporter) generatePrometheusMetrics(ctx context.Context, db queryer, parse func(row row) error, query string,
+	args []interface{}, queryTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
 	defer cancel()
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query, args...)
 
 	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-		return errors.New("oracle query timed out")
+		return errQueryTimeout
 	}
 
 	if err != nil {
 		return err
 	}
-	cols, err := rows.Columns()
-	// fmt.Printf("___________ query: %s, cols: %+v\n", query, cols)
 	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
 
 	for rows.Next() {
 		// Create a slice of interface{}'s to represent each column,
 		// and a second slice to contain pointers to each item in the columns slice.
-		columns := make([]interface{}, len(cols))
-		columnPointers := make([]interface{}, len(cols))
+		columns := make([]interface{}, len(columnTypes))
+		columnPointers := make([]interface{}, len(columnTypes))
 		for i := range columns {
 			columnPointers[i] = &columns[i]
 		}
@@ -640,19 +1144,21 @@ func (e *Exporter) generatePrometheusMetrics(db *sql.DB, parse func(row map[stri
 			return err
 		}
 
-		// Create our map, and retrieve the value for each column from the pointers slice,
+		// Create our map, and convert the value of each column according to its type,
 		// storing it in the map with the name of the column as the key.
-		m := make(map[string]string)
-		for i, colName := range cols {
-			val := columnPointers[i].(*interface{})
-			m[strings.ToLower(colName)] = fmt.Sprintf("%v", *val)
+		m := make(row, len(columnTypes))
+		for i, columnType := range columnTypes {
+			m[strings.ToLower(columnType.Name())] = newColumnValue(columns[i], columnType.DatabaseTypeName())
 		}
 		// Call function to parse row
 		if err := parse(m); err != nil {
 			return err
 		}
 	}
-	return nil
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errQueryTimeout
+	}
+	return rows.Err()
 }
 
 func getMetricType(metricType string, metricsType map[string]string) prometheus.ValueType {
@@ -660,6 +1166,8 @@ func getMetricType(metricType string, metricsType map[string]string) prometheus.
 		"gauge":     prometheus.GaugeValue,
 		"counter":   prometheus.CounterValue,
 		"histogram": prometheus.UntypedValue,
+		"info":      prometheus.GaugeValue,
+		"summary":   prometheus.UntypedValue,
 	}
 
 	strType, ok := metricsType[strings.ToLower(metricType)]
@@ -673,6 +1181,50 @@ func getMetricType(metricType string, metricsType map[string]string) prometheus.
 	return valueType
 }
 
+// buildMetricName returns the name of the series of the metricsdesc entry
+// metric, column being the column name, or the field content when the metric
+// uses fieldtoappend. The name is namespace_context_column, unless a
+// metricsname template is defined for metric, in which {{namespace}},
+// {{context}} and {{column}} are replaced.
+func buildMetricName(namespace string, metricDefinition Metric, metric, column string) (string, error) {
+	template, ok := metricDefinition.MetricsName[metric]
+	if !ok {
+		name := prometheus.BuildFQName(namespace, metricDefinition.Context, column)
+		if !metricNamePattern.MatchString(name) {
+			return "", fmt.Errorf("invalid metric name %q", name)
+		}
+		return name, nil
+	}
+	name := strings.NewReplacer(
+		"{{namespace}}", namespace,
+		"{{context}}", metricDefinition.Context,
+		"{{column}}", column,
+	).Replace(template)
+	if !metricNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid metric name %q built from template %q", name, template)
+	}
+	return name, nil
+}
+
+// appendedName returns the part of the metric name given by the fields of
+// fieldToAppend, a comma separated list of columns whose contents are joined
+// with underscores.
+func appendedName(row row, fieldToAppend string) string {
+	var parts []string
+	for _, field := range strings.Split(fieldToAppend, ",") {
+		parts = append(parts, cleanName(row[strings.ToLower(strings.TrimSpace(field))].String()))
+	}
+	return strings.Join(parts, "_")
+}
+
+// infoName returns the name of an info metric, which ends with _info.
+func infoName(name string) string {
+	if strings.HasSuffix(name, "_info") {
+		return name
+	}
+	return name + "_info"
+}
+
 func cleanName(s string) string {
 	s = strings.ReplaceAll(s, " ", "_") // Remove spaces
 	s = strings.ReplaceAll(s, "-", "_") // Remove hyphens
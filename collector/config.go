@@ -0,0 +1,254 @@
+package collector
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"sigs.k8s.io/yaml"
+)
+
+// FileConfig is the content of the exporter configuration file, in a toml or
+// yaml format.
+type FileConfig struct {
+	Namespace      string
+	SessionInit    []string
+	ConstLabels    map[string]string
+	IdentityLabels bool
+	Databases      map[string]Database
+	Modules        map[string]Module
+}
+
+// Database is a named database scraped through the telemetry path. Empty
+// fields are inherited from the command line configuration.
+type Database struct {
+	DSN            string
+	DSNFile        string
+	MaxIdleConns   int
+	MaxOpenConns   int
+	DefaultMetrics string
+	CustomMetrics  string
+	QueryTimeout   int
+	ScrapeWorkers  int
+	Labels         map[string]string
+	Params         map[string]string
+	SessionInit    []string
+	Namespace      string
+}
+
+// Module is a named set of settings used by the /probe endpoint. Empty fields
+// are inherited from the command line configuration.
+type Module struct {
+	DSN            string
+	Username       string
+	Password       string
+	DefaultMetrics string
+	CustomMetrics  string
+	QueryTimeout   int
+	Params         map[string]string
+	SessionInit    []string
+	Namespace      string
+}
+
+// LoadFileConfig reads the exporter configuration file.
+func LoadFileConfig(fileName string) (*FileConfig, error) {
+	cfg := &FileConfig{}
+	if strings.HasSuffix(fileName, "toml") {
+		if _, err := toml.DecodeFile(fileName, cfg); err != nil {
+			return nil, fmt.Errorf("cannot read the exporter config %s: %w", fileName, err)
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, fmt.Errorf("invalid exporter config %s: %w", fileName, err)
+		}
+		return cfg, nil
+	}
+	yamlBytes, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the exporter config %s: %w", fileName, err)
+	}
+	if err := yaml.Unmarshal(yamlBytes, cfg); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal the exporter config %s: %w", fileName, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid exporter config %s: %w", fileName, err)
+	}
+	return cfg, nil
+}
+
+// validate checks the settings which would only fail once metrics are
+// scraped.
+func (c *FileConfig) validate() error {
+	if err := checkConstLabels(c.ConstLabels); err != nil {
+		return fmt.Errorf("constlabels: %w", err)
+	}
+	for name, database := range c.Databases {
+		if err := checkConstLabels(database.Labels); err != nil {
+			return fmt.Errorf("labels of database %s: %w", name, err)
+		}
+		if database.Namespace != "" {
+			if err := ValidateNamespace(database.Namespace); err != nil {
+				return fmt.Errorf("database %s: %w", name, err)
+			}
+		}
+	}
+	for name, module := range c.Modules {
+		if module.Namespace != "" {
+			if err := ValidateNamespace(module.Namespace); err != nil {
+				return fmt.Errorf("module %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// exporterLabels are the labels set by the exporter itself, which constant
+// labels cannot use.
+var exporterLabels = []string{"database", "con_name", "inst_id", "instance_name", "dbid", "db_unique_name"}
+
+// checkConstLabels checks the names of constant labels.
+func checkConstLabels(labels map[string]string) error {
+	for name := range labels {
+		if err := checkConstLabelName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConstLabelName checks the name of a constant label.
+func checkConstLabelName(name string) error {
+	if !validLabelName(name) {
+		return fmt.Errorf("invalid label name %q", name)
+	}
+	if slices.Contains(exporterLabels, name) {
+		return fmt.Errorf("label %s is set by the exporter", name)
+	}
+	return nil
+}
+
+// Config returns the configuration used to scrape the database registered
+// under name, base providing the settings the database does not override.
+// Every series of the database is labelled with database="<name>" and the
+// labels of the database.
+func (d Database) Config(name string, base *Config) (*Config, error) {
+	cfg := *base
+	cfg.DSN = d.DSN
+	if d.DSNFile != "" {
+		dsnFileContent, err := os.ReadFile(d.DSNFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the DSN file of database %s: %w", name, err)
+		}
+		cfg.DSN = strings.TrimSpace(string(dsnFileContent))
+	}
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("no DSN defined for database %s", name)
+	}
+	if d.MaxIdleConns != 0 {
+		cfg.MaxIdleConns = d.MaxIdleConns
+	}
+	if d.MaxOpenConns != 0 {
+		cfg.MaxOpenConns = d.MaxOpenConns
+	}
+	if d.DefaultMetrics != "" {
+		cfg.DefaultMetricsFile = d.DefaultMetrics
+	}
+	if d.CustomMetrics != "" {
+		cfg.CustomMetrics = d.CustomMetrics
+	}
+	if d.QueryTimeout != 0 {
+		cfg.QueryTimeout = d.QueryTimeout
+	}
+	if d.ScrapeWorkers != 0 {
+		cfg.ScrapeWorkers = d.ScrapeWorkers
+	}
+	if d.Namespace != "" {
+		if err := ValidateNamespace(d.Namespace); err != nil {
+			return nil, fmt.Errorf("database %s: %w", name, err)
+		}
+		cfg.Namespace = d.Namespace
+	}
+	cfg.ConstLabels = mergeMaps(base.ConstLabels, d.Labels)
+	cfg.ConstLabels["database"] = name
+	cfg.Params = mergeMaps(base.Params, d.Params)
+	cfg.SessionInit = append(append([]string{}, base.SessionInit...), d.SessionInit...)
+	return &cfg, nil
+}
+
+// Config returns the configuration used to probe target with this module,
+// base providing the settings the module does not override.
+func (m Module) Config(base *Config, target string) (*Config, error) {
+	dsnTemplate := base.DSN
+	if m.DSN != "" {
+		dsnTemplate = m.DSN
+	}
+	dsn, err := TargetDSN(dsnTemplate, target, base.AllowTargetDSN)
+	if err != nil {
+		return nil, err
+	}
+	if m.Username != "" {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return nil, err
+		}
+		u.User = url.UserPassword(m.Username, m.Password)
+		dsn = u.String()
+	}
+
+	cfg := *base
+	cfg.DSN = dsn
+	if m.DefaultMetrics != "" {
+		cfg.DefaultMetricsFile = m.DefaultMetrics
+	}
+	if m.CustomMetrics != "" {
+		cfg.CustomMetrics = m.CustomMetrics
+	}
+	if m.QueryTimeout != 0 {
+		cfg.QueryTimeout = m.QueryTimeout
+	}
+	if m.Namespace != "" {
+		if err := ValidateNamespace(m.Namespace); err != nil {
+			return nil, err
+		}
+		cfg.Namespace = m.Namespace
+	}
+	cfg.Params = mergeMaps(base.Params, m.Params)
+	cfg.SessionInit = append(append([]string{}, base.SessionInit...), m.SessionInit...)
+	return &cfg, nil
+}
+
+// ParseConstLabels parses constant labels given as a comma separated list of
+// name=value pairs.
+func ParseConstLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || !labelNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid constant label %q, expected name=value", pair)
+		}
+		if err := checkConstLabelName(name); err != nil {
+			return nil, fmt.Errorf("invalid constant label %q: %w", pair, err)
+		}
+		labels[name] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// mergeMaps returns a new map holding the entries of base overridden by the
+// ones of override.
+func mergeMaps(base, override map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeContainers runs the request of metric in each pluggable database
+// selected by its include and exclude patterns. The series are labelled with
+// the name of their pluggable database (con_name).
+func (e *Exporter) scrapeContainers(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric, metric Metric, q query,
+	containers []string) error {
+	include, err := compilePatterns(metric.ContainerInclude)
+	if err != nil {
+		return fmt.Errorf("invalid containerinclude: %w", err)
+	}
+	exclude, err := compilePatterns(metric.ContainerExclude)
+	if err != nil {
+		return fmt.Errorf("invalid containerexclude: %w", err)
+	}
+
+	var errs []error
+	for _, container := range containers {
+		if (len(include) > 0 && !matchAny(include, container)) || matchAny(exclude, container) {
+			continue
+		}
+		containerQuery := q
+		containerQuery.constLabels = mergeMaps(q.constLabels, map[string]string{"con_name": container})
+		if err := e.scrapeContainer(ctx, db, ch, metric, containerQuery, container); err != nil {
+			errs = append(errs, fmt.Errorf("container %s: %w", container, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// scrapeContainer runs the request of metric in container, using a connection
+// switched to this container for the time of the request.
+func (e *Exporter) scrapeContainer(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric, metric Metric, q query,
+	container string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ALTER SESSION SET CONTAINER = "`+container+`"`); err != nil {
+		return err
+	}
+	defer func() {
+		// The scrape context may be done already, use a fresh one to switch back
+		resetCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(resetCtx, "ALTER SESSION SET CONTAINER = CDB$ROOT"); err != nil {
+			e.logger.Debugw("Unable to switch back to the root container, discarding connection", "error", err)
+			// Never give back to the pool a connection bound to a pluggable database
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+	return e.scrapeGenericValues(ctx, conn, ch, metric, q)
+}
+
+// compilePatterns compiles patterns, which must match a whole name.
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func matchAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCheckMetricLabelsPerContainer(t *testing.T) {
+	e := &Exporter{config: &Config{}}
+	metric := Metric{Context: "sessions", Labels: []string{"con_name", "status"}}
+	assert.NoError(t, e.checkMetricLabels(metric))
+
+	metric.PerContainer = true
+	assert.ErrorContains(t, e.checkMetricLabels(metric), "percontainer")
+}
+
+// containerDatabase is a fake container database recording the statements
+// and requests it runs. Requests fail in the failing container.
+type containerDatabase struct {
+	container  string
+	failing    string
+	statements []string
+}
+
+func (d *containerDatabase) exec(_ context.Context, statement string) error {
+	d.statements = append(d.statements, statement)
+	if name, ok := strings.CutPrefix(statement, "ALTER SESSION SET CONTAINER = "); ok {
+		d.container = strings.Trim(name, `"`)
+	}
+	return nil
+}
+
+func (d *containerDatabase) query(_ context.Context, query string) (fakeResult, error) {
+	d.statements = append(d.statements, query)
+	if d.container == d.failing {
+		return fakeResult{}, errors.New("ORA-00942: table or view does not exist")
+	}
+	return fakeResult{columns: []string{"VALUE"}, types: []string{"NUMBER"}, rows: [][]driver.Value{{"3"}}}, nil
+}
+
+func TestScrapeContainers(t *testing.T) {
+	metric := Metric{
+		Context:          "sessions",
+		MetricsDesc:      map[string]string{"value": "Number of sessions."},
+		Request:          "SELECT COUNT(*) AS value FROM v$session",
+		PerContainer:     true,
+		ContainerInclude: []string{"PDB.*", "TEST1"},
+		ContainerExclude: []string{"PDB2"},
+	}
+	database := &containerDatabase{failing: "TEST1"}
+	db := sql.OpenDB(fakeResultConnector{query: database.query, exec: database.exec})
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	e := &Exporter{config: &Config{}, logger: zaptest.NewLogger(t).Sugar()}
+
+	ch := make(chan prometheus.Metric, 10)
+	q := query{request: metric.Request, timeout: time.Second, constLabels: prometheus.Labels{"env": "prod"}}
+	err := e.scrapeContainers(context.Background(), db, ch, metric, q, []string{"PDB1", "PDB2", "TEST1", "TEST2"})
+	assert.ErrorContains(t, err, "container TEST1")
+	assert.Equal(t, []string{
+		`ALTER SESSION SET CONTAINER = "PDB1"`,
+		metric.Request,
+		"ALTER SESSION SET CONTAINER = CDB$ROOT",
+		`ALTER SESSION SET CONTAINER = "TEST1"`,
+		metric.Request,
+		"ALTER SESSION SET CONTAINER = CDB$ROOT",
+	}, database.statements)
+
+	close(ch)
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(metricsCollector(ch))
+	err = testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP oracledb_sessions_value Number of sessions.
+# TYPE oracledb_sessions_value gauge
+oracledb_sessions_value{con_name="PDB1",env="prod"} 3
+`))
+	assert.NoError(t, err)
+}
+
+// metricsCollector collects the metrics sent to a closed channel.
+type metricsCollector chan prometheus.Metric
+
+func (c metricsCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for m := range c {
+		ch <- m
+	}
+}
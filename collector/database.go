@@ -0,0 +1,230 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// databaseInfo holds the facts about the scraped database used to select the
+// metrics to run. Empty fields are unknown. The version is discovered once
+// connected, the role and open mode are read on every scrape as they change
+// on switchovers.
+type databaseInfo struct {
+	version  string
+	role     string
+	openMode string
+	// containers lists the open pluggable databases of a container database
+	containers []string
+	// cluster tells if the database is a RAC database, whose open instances
+	// are listed by instances
+	cluster   bool
+	instances []instance
+	// dbid and dbUniqueName identify the database
+	dbid         string
+	dbUniqueName string
+}
+
+// discoverDatabase reads the facts about the database the exporter is
+// connected to.
+func (e *Exporter) discoverDatabase(ctx context.Context) (*databaseInfo, error) {
+	info := &databaseInfo{}
+	// Since 18c, version only holds the base release, version_full holding
+	// the release update
+	if err := e.db.QueryRowContext(ctx, "SELECT version_full FROM v$instance").Scan(&info.version); err != nil {
+		e.logger.Debugw("Unable to read version_full, reading version", "error", err)
+		if err := e.db.QueryRowContext(ctx, "SELECT version FROM v$instance").Scan(&info.version); err != nil {
+			return nil, fmt.Errorf("unable to read the database version: %w", err)
+		}
+	}
+	var clusterDatabase string
+	err := e.db.QueryRowContext(ctx, "SELECT value FROM v$parameter WHERE name = 'cluster_database'").Scan(&clusterDatabase)
+	if err != nil {
+		e.logger.Debugw("Unable to read the cluster_database parameter, assuming a single instance database", "error", err)
+	}
+	info.cluster = strings.EqualFold(clusterDatabase, "TRUE")
+	err = e.db.QueryRowContext(ctx, "SELECT TO_CHAR(dbid), db_unique_name FROM v$database").Scan(&info.dbid, &info.dbUniqueName)
+	if err != nil {
+		e.logger.Debugw("Unable to read the identity of the database", "error", err)
+	}
+	return info, nil
+}
+
+// discoveryRetryInterval is the time after which a failed discovery of the
+// database is retried.
+const discoveryRetryInterval = 5 * time.Minute
+
+// needsDiscovery tells if some metric or setting depends on the version, the
+// cluster mode or the identity of the database.
+func (e *Exporter) needsDiscovery() bool {
+	if e.config.IdentityLabels {
+		return true
+	}
+	for _, metric := range e.metricsToScrape.Metric {
+		if metric.MinVersion != "" || metric.MaxVersion != "" || metric.PerInstance {
+			return true
+		}
+	}
+	return false
+}
+
+// readDatabaseState reads the current role and open mode of the database into
+// info.
+func (e *Exporter) readDatabaseState(ctx context.Context, info *databaseInfo) error {
+	err := e.db.QueryRowContext(ctx, "SELECT database_role, open_mode FROM v$database").Scan(&info.role, &info.openMode)
+	if err != nil {
+		return fmt.Errorf("unable to read the database role and open mode: %w", err)
+	}
+	return nil
+}
+
+// needsDatabaseState tells if some metric depends on the role or the open
+// mode of the database.
+func (e *Exporter) needsDatabaseState() bool {
+	for _, metric := range e.metricsToScrape.Metric {
+		if dependsOnDatabaseState(metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOnDatabaseState tells if metric is restricted to some roles or open
+// modes of the database.
+func dependsOnDatabaseState(metric Metric) bool {
+	return len(metric.DatabaseRole) > 0 || len(metric.OpenMode) > 0
+}
+
+// readContainers lists the open pluggable databases into info.
+func (e *Exporter) readContainers(ctx context.Context, info *databaseInfo) error {
+	rows, err := e.db.QueryContext(ctx, `SELECT name FROM v$pdbs
+WHERE open_mode IN ('READ WRITE', 'READ ONLY') AND name <> 'PDB$SEED' ORDER BY name`)
+	if err != nil {
+		return fmt.Errorf("unable to list the pluggable databases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		info.containers = append(info.containers, name)
+	}
+	return rows.Err()
+}
+
+// readInstances lists the open instances of the database into info.
+func (e *Exporter) readInstances(ctx context.Context, info *databaseInfo) error {
+	rows, err := e.db.QueryContext(ctx, "SELECT TO_CHAR(inst_id), instance_name FROM gv$instance ORDER BY inst_id")
+	if err != nil {
+		return fmt.Errorf("unable to list the instances: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var inst instance
+		if err := rows.Scan(&inst.id, &inst.name); err != nil {
+			return err
+		}
+		info.instances = append(info.instances, inst)
+	}
+	return rows.Err()
+}
+
+// needsInstances tells if some metric is run on every instance.
+func (e *Exporter) needsInstances() bool {
+	for _, metric := range e.metricsToScrape.Metric {
+		if metric.PerInstance {
+			return true
+		}
+	}
+	return false
+}
+
+// needsContainers tells if some metric is run in every pluggable database.
+func (e *Exporter) needsContainers() bool {
+	for _, metric := range e.metricsToScrape.Metric {
+		if metric.PerContainer {
+			return true
+		}
+	}
+	return false
+}
+
+// isMetricEnabled tells if metric applies to the database described by info.
+// The metrics having a condition on an unknown fact are disabled.
+func (e *Exporter) isMetricEnabled(metric Metric, info databaseInfo) bool {
+	if len(metric.DatabaseRole) > 0 && !containsFold(metric.DatabaseRole, info.role) {
+		return false
+	}
+	if len(metric.OpenMode) > 0 && !containsFold(metric.OpenMode, info.openMode) {
+		return false
+	}
+	if metric.MinVersion == "" && metric.MaxVersion == "" {
+		return true
+	}
+	if info.version == "" {
+		return false
+	}
+	if metric.MinVersion != "" {
+		cmp, err := compareVersions(info.version, metric.MinVersion)
+		if err != nil {
+			e.logger.Errorw("Invalid minversion", "context", metric.Context, "error", err)
+			return false
+		}
+		if cmp < 0 {
+			return false
+		}
+	}
+	if metric.MaxVersion != "" {
+		cmp, err := compareVersions(info.version, metric.MaxVersion)
+		if err != nil {
+			e.logger.Errorw("Invalid maxversion", "context", metric.Context, "error", err)
+			return false
+		}
+		if cmp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold tells if value, when known, is one of values regardless of
+// the case.
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareVersions compares the dotted version to bound, on the components
+// given by bound only: 12.2.0.1 is equal to the bound 12 and to the bound 12.2.
+func compareVersions(version, bound string) (int, error) {
+	versionParts := strings.Split(strings.TrimSpace(version), ".")
+	for i, boundPart := range strings.Split(strings.TrimSpace(bound), ".") {
+		b, err := strconv.Atoi(boundPart)
+		if err != nil {
+			return 0, fmt.Errorf("invalid version %q", bound)
+		}
+		v := 0
+		if i < len(versionParts) {
+			if v, err = strconv.Atoi(versionParts[i]); err != nil {
+				return 0, fmt.Errorf("invalid version %q", version)
+			}
+		}
+		if v != b {
+			if v < b {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
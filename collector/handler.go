@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ScrapeContext returns the context of the scrape served by r. It is
+// cancelled when the client goes away and, when Prometheus gives its scrape
+// timeout in the X-Prometheus-Scrape-Timeout-Seconds header, once this
+// timeout minus offset has elapsed.
+func ScrapeContext(r *http.Request, offset time.Duration) (context.Context, context.CancelFunc) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		return context.WithCancel(r.Context())
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > offset {
+		timeout -= offset
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// MetricsHandler returns a handler serving the metrics of gatherer and the
+// ones of exporters, scraped within the context of the request (see
+// ScrapeContext).
+func MetricsHandler(gatherer prometheus.Gatherer, exporters []*Exporter, timeoutOffset time.Duration, opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveMetrics(w, r, gatherer, exporters, timeoutOffset, opts)
+	})
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request, gatherer prometheus.Gatherer, exporters []*Exporter,
+	timeoutOffset time.Duration, opts promhttp.HandlerOpts) {
+	ctx, cancel := ScrapeContext(r, timeoutOffset)
+	defer cancel()
+
+	registry := prometheus.NewRegistry()
+	for _, exporter := range exporters {
+		registry.MustRegister(exporter.WithContext(ctx))
+	}
+	gatherers := prometheus.Gatherers{registry}
+	if gatherer != nil {
+		gatherers = append(gatherers, gatherer)
+	}
+	promhttp.HandlerFor(gatherers, opts).ServeHTTP(w, r)
+}
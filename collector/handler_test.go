@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrapeContext(t *testing.T) {
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	ctx, cancel := ScrapeContext(r, 500*time.Millisecond)
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancel()
+
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "10")
+	ctx, cancel = ScrapeContext(r, 500*time.Millisecond)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(9500*time.Millisecond), deadline, time.Second)
+}
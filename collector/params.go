@@ -0,0 +1,50 @@
+package collector
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// bindArgs returns the bind variables of the metric request. The value of a
+// parameter is either:
+//   - env:NAME, the value of the environment variable NAME
+//   - config:NAME, the parameter NAME of the exporter configuration
+//   - any other string, used literally
+func (e *Exporter) bindArgs(metricDefinition Metric) ([]interface{}, error) {
+	names := make([]string, 0, len(metricDefinition.Params))
+	for name := range metricDefinition.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		value, err := e.paramValue(metricDefinition.Params[name])
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve parameter %s: %w", name, err)
+		}
+		args = append(args, sql.Named(name, value))
+	}
+	return args, nil
+}
+
+func (e *Exporter) paramValue(param string) (string, error) {
+	if name, ok := strings.CutPrefix(param, "env:"); ok {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	if name, ok := strings.CutPrefix(param, "config:"); ok {
+		value, ok := e.config.Params[name]
+		if !ok {
+			return "", fmt.Errorf("parameter %s is not defined in the configuration", name)
+		}
+		return value, nil
+	}
+	return param, nil
+}
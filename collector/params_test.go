@@ -0,0 +1,28 @@
+package collector
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindArgs(t *testing.T) {
+	t.Setenv("ORACLEDB_EXPORTER_TEST_SCHEMA", "APP")
+	e := &Exporter{config: &Config{Params: map[string]string{"threshold": "90"}}}
+
+	args, err := e.bindArgs(Metric{Params: map[string]string{
+		"schema":    "env:ORACLEDB_EXPORTER_TEST_SCHEMA",
+		"threshold": "config:threshold",
+		"status":    "VALID",
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		sql.Named("schema", "APP"),
+		sql.Named("status", "VALID"),
+		sql.Named("threshold", "90"),
+	}, args)
+
+	_, err = e.bindArgs(Metric{Params: map[string]string{"missing": "config:missing"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// instance is an instance of a (RAC) database.
+type instance struct {
+	id   string
+	name string
+}
+
+// instanceBindName is the bind variable holding the instance id in the
+// requests rewritten by perInstanceRequest.
+const instanceBindName = "exporter_inst_id"
+
+// viewPattern matches the references to v$ views, but not to gv$ views nor
+// to qualified names (sys.v$session). String literals, quoted identifiers and
+// comments are matched as a whole, so that the views they mention are skipped.
+var viewPattern = regexp.MustCompile(`(?is)'[^']*'|"[^"]*"|--[^\n]*|/\*.*?\*/|(^|[^\w$#.])v\$(\w+)`)
+
+// perInstanceRequest rewrites request so that each v$ view is replaced by the
+// rows of the corresponding gv$ view for the instance given by the
+// :exporter_inst_id bind variable.
+func perInstanceRequest(request string) string {
+	var rewritten strings.Builder
+	last := 0
+	for _, match := range viewPattern.FindAllStringSubmatchIndex(request, -1) {
+		if match[4] < 0 {
+			// Quoted text or comment
+			continue
+		}
+		rewritten.WriteString(request[last:match[3]])
+		rewritten.WriteString("(SELECT * FROM gv$" + request[match[4]:match[5]] + " WHERE inst_id = :" + instanceBindName + ")")
+		last = match[5]
+	}
+	rewritten.WriteString(request[last:])
+	return rewritten.String()
+}
+
+// instanceQueries returns the queries running q on each instance of a
+// cluster database. The series are labelled with inst_id and instance_name.
+// On a single instance database, q is run as is.
+func instanceQueries(q query, info databaseInfo) []query {
+	queries := make([]query, 0, len(info.instances))
+	for _, inst := range info.instances {
+		instanceQuery := q
+		instanceQuery.constLabels = mergeMaps(q.constLabels, map[string]string{
+			"inst_id":       inst.id,
+			"instance_name": inst.name,
+		})
+		if info.cluster {
+			instanceQuery.request = perInstanceRequest(q.request)
+			instanceQuery.args = append(append([]interface{}{}, q.args...), sql.Named(instanceBindName, inst.id))
+		}
+		queries = append(queries, instanceQuery)
+	}
+	return queries
+}
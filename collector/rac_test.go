@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerInstanceRequest(t *testing.T) {
+	assert.Equal(t,
+		"SELECT status, COUNT(*) AS value FROM (SELECT * FROM gv$session WHERE inst_id = :exporter_inst_id) s GROUP BY status",
+		perInstanceRequest("SELECT status, COUNT(*) AS value FROM v$session s GROUP BY status"))
+	assert.Equal(t,
+		"SELECT a.name FROM (SELECT * FROM gv$SYSSTAT WHERE inst_id = :exporter_inst_id) a,(SELECT * FROM gv$instance WHERE inst_id = :exporter_inst_id) i",
+		perInstanceRequest("SELECT a.name FROM V$SYSSTAT a,v$instance i"))
+	assert.Equal(t, "SELECT COUNT(*) FROM gv$session", perInstanceRequest("SELECT COUNT(*) FROM gv$session"))
+	assert.Equal(t, "SELECT COUNT(*) FROM sys.v$session", perInstanceRequest("SELECT COUNT(*) FROM sys.v$session"))
+	assert.Equal(t,
+		"SELECT 'v$session' AS name, \"v$x\" FROM (SELECT * FROM gv$session WHERE inst_id = :exporter_inst_id) -- v$session\n/* v$session */",
+		perInstanceRequest("SELECT 'v$session' AS name, \"v$x\" FROM v$session -- v$session\n/* v$session */"))
+}
+
+func TestInstanceQueries(t *testing.T) {
+	q := query{request: "SELECT COUNT(*) AS value FROM v$session", constLabels: map[string]string{"env": "prod"}}
+	info := databaseInfo{cluster: true, instances: []instance{{id: "1", name: "orcl1"}, {id: "2", name: "orcl2"}}}
+
+	queries := instanceQueries(q, info)
+	assert.Len(t, queries, 2)
+	assert.Equal(t, "SELECT COUNT(*) AS value FROM (SELECT * FROM gv$session WHERE inst_id = :exporter_inst_id)", queries[1].request)
+	assert.Equal(t, []interface{}{sql.Named("exporter_inst_id", "2")}, queries[1].args)
+	assert.Equal(t, map[string]string{"env": "prod", "inst_id": "2", "instance_name": "orcl2"}, map[string]string(queries[1].constLabels))
+
+	info.cluster = false
+	info.instances = info.instances[:1]
+	queries = instanceQueries(q, info)
+	assert.Equal(t, q.request, queries[0].request)
+	assert.Equal(t, "orcl1", queries[0].constLabels["instance_name"])
+}
+
+func TestCheckMetricLabelsPerInstance(t *testing.T) {
+	e := &Exporter{config: &Config{}}
+	metric := Metric{Context: "asmuptime", Labels: []string{"inst_id", "node_name", "instance_name"}}
+	assert.NoError(t, e.checkMetricLabels(metric))
+
+	metric.PerInstance = true
+	assert.ErrorContains(t, e.checkMetricLabels(metric), "perinstance")
+
+	metric.Labels = []string{"node_name"}
+	assert.NoError(t, e.checkMetricLabels(metric))
+}
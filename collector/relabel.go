@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validLabelName tells if name can be the name of a label of a series, names
+// starting with __ being reserved by Prometheus.
+func validLabelName(name string) bool {
+	return labelNamePattern.MatchString(name) && !strings.HasPrefix(name, "__")
+}
+
+// Relabel transforms the labels of the series of a metric, as the relabel
+// configs of Prometheus do. The action is one of:
+//   - replace (default): sets TargetLabel to Replacement, where $1... refer to
+//     the groups of Regex, when the value of SourceLabel matches Regex
+//   - rename: moves the value of SourceLabel to TargetLabel
+//   - lowercase, uppercase: sets TargetLabel to the value of SourceLabel in
+//     lower or upper case
+//   - truncate: sets TargetLabel to the first Length characters of the value
+//     of SourceLabel
+//   - hash: sets TargetLabel to a hash of the value of SourceLabel
+//   - drop, keep: drops the series whose value of SourceLabel matches, or
+//     does not match, Regex
+//
+// TargetLabel defaults to SourceLabel, so that actions change the label in
+// place, and Regex is anchored at both ends.
+type Relabel struct {
+	SourceLabel string
+	TargetLabel string
+	Action      string
+	Regex       string
+	Replacement string
+	Length      int
+}
+
+type relabelRule struct {
+	Relabel
+	regex *regexp.Regexp
+}
+
+// compileRelabel checks the relabel configuration of a metric and compiles
+// its regular expressions.
+func compileRelabel(relabels []Relabel) ([]relabelRule, error) {
+	rules := make([]relabelRule, 0, len(relabels))
+	for _, r := range relabels {
+		r.Action = strings.ToLower(r.Action)
+		if r.Action == "" {
+			r.Action = "replace"
+		}
+		if r.SourceLabel == "" {
+			return nil, fmt.Errorf("relabel %s: sourcelabel is missing", r.Action)
+		}
+		if r.TargetLabel == "" {
+			if r.Action == "rename" {
+				return nil, fmt.Errorf("relabel rename of %s: targetlabel is missing", r.SourceLabel)
+			}
+			r.TargetLabel = r.SourceLabel
+		}
+		if !validLabelName(r.TargetLabel) {
+			return nil, fmt.Errorf("relabel %s of %s: invalid label name %q", r.Action, r.SourceLabel, r.TargetLabel)
+		}
+		if r.Regex == "" {
+			r.Regex = "(.*)"
+		}
+		if r.Replacement == "" {
+			r.Replacement = "$1"
+		}
+		regex, err := regexp.Compile("^(?:" + r.Regex + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabel %s of %s: %w", r.Action, r.SourceLabel, err)
+		}
+		switch r.Action {
+		case "replace", "rename", "lowercase", "uppercase", "hash", "drop", "keep":
+		case "truncate":
+			if r.Length <= 0 {
+				return nil, fmt.Errorf("relabel truncate of %s: length must be positive", r.SourceLabel)
+			}
+		default:
+			return nil, fmt.Errorf("relabel of %s: unknown action %q", r.SourceLabel, r.Action)
+		}
+		rules = append(rules, relabelRule{Relabel: r, regex: regex})
+	}
+	return rules, nil
+}
+
+// relabel applies rules to the labels of a series, given as names and values,
+// and returns the resulting labels. keep is false when the series is dropped.
+func relabel(rules []relabelRule, names, values []string) (newNames, newValues []string, keep bool) {
+	if len(rules) == 0 {
+		return names, values, true
+	}
+	newNames = append([]string{}, names...)
+	newValues = append([]string{}, values...)
+	set := func(name, value string) {
+		for i, n := range newNames {
+			if n == name {
+				newValues[i] = value
+				return
+			}
+		}
+		newNames = append(newNames, name)
+		newValues = append(newValues, value)
+	}
+
+	for _, rule := range rules {
+		source := -1
+		for i, n := range newNames {
+			if n == rule.SourceLabel {
+				source = i
+				break
+			}
+		}
+		var value string
+		if source >= 0 {
+			value = newValues[source]
+		}
+		switch rule.Action {
+		case "replace":
+			if match := rule.regex.FindStringSubmatchIndex(value); match != nil {
+				set(rule.TargetLabel, string(rule.regex.ExpandString(nil, rule.Replacement, value, match)))
+			}
+		case "rename":
+			if source >= 0 {
+				newNames = append(newNames[:source], newNames[source+1:]...)
+				newValues = append(newValues[:source], newValues[source+1:]...)
+			}
+			set(rule.TargetLabel, value)
+		case "lowercase":
+			set(rule.TargetLabel, strings.ToLower(value))
+		case "uppercase":
+			set(rule.TargetLabel, strings.ToUpper(value))
+		case "truncate":
+			if runes := []rune(value); len(runes) > rule.Length {
+				value = string(runes[:rule.Length])
+			}
+			set(rule.TargetLabel, value)
+		case "hash":
+			sum := sha256.Sum256([]byte(value))
+			set(rule.TargetLabel, hex.EncodeToString(sum[:8]))
+		case "drop":
+			if rule.regex.MatchString(value) {
+				return nil, nil, false
+			}
+		case "keep":
+			if !rule.regex.MatchString(value) {
+				return nil, nil, false
+			}
+		}
+	}
+	return newNames, newValues, true
+}
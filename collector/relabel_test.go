@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelabel(t *testing.T) {
+	rules, err := compileRelabel([]Relabel{
+		{SourceLabel: "path", TargetLabel: "disk", Regex: ".*/([^/]+)"},
+		{SourceLabel: "diskgroup_name", TargetLabel: "diskgroup", Action: "rename"},
+		{SourceLabel: "diskgroup", Action: "lowercase"},
+		{SourceLabel: "sql_text", Action: "truncate", Length: 6},
+		{SourceLabel: "sql_text", TargetLabel: "sql_hash", Action: "hash"},
+		{SourceLabel: "diskgroup", Action: "drop", Regex: "reco.*"},
+	})
+	assert.NoError(t, err)
+
+	names, values, keep := relabel(rules,
+		[]string{"diskgroup_name", "path", "sql_text"},
+		[]string{"DATA", "/dev/oracleasm/disks/DISK1", "SELECT * FROM dual"})
+	assert.True(t, keep)
+	assert.Equal(t, []string{"path", "sql_text", "disk", "diskgroup", "sql_hash"}, names)
+	assert.Equal(t, "/dev/oracleasm/disks/DISK1", values[0])
+	assert.Equal(t, "SELECT", values[1])
+	assert.Equal(t, "DISK1", values[2])
+	assert.Equal(t, "data", values[3])
+	assert.Len(t, values[4], 16)
+
+	_, _, keep = relabel(rules, []string{"diskgroup_name", "path", "sql_text"}, []string{"RECO", "", ""})
+	assert.False(t, keep)
+
+	rules, err = compileRelabel([]Relabel{{SourceLabel: "status", Action: "keep", Regex: "VALID|ONLINE"}})
+	assert.NoError(t, err)
+	_, _, keep = relabel(rules, []string{"status"}, []string{"OFFLINE"})
+	assert.False(t, keep)
+	_, _, keep = relabel(rules, []string{"status"}, []string{"ONLINE"})
+	assert.True(t, keep)
+}
+
+func TestCompileRelabelErrors(t *testing.T) {
+	for _, r := range []Relabel{
+		{Action: "lowercase"},
+		{SourceLabel: "path", Action: "rename"},
+		{SourceLabel: "path", TargetLabel: "bad-name"},
+		{SourceLabel: "status", TargetLabel: "__tmp_status"},
+		{SourceLabel: "path", Regex: "("},
+		{SourceLabel: "path", Action: "truncate"},
+		{SourceLabel: "path", Action: "explode"},
+	} {
+		_, err := compileRelabel([]Relabel{r})
+		assert.Error(t, err, r)
+	}
+}
+
+func TestApplyFileSettingsRelabel(t *testing.T) {
+	metrics := Metrics{Metric: []Metric{{
+		Context: "sessions",
+		Relabel: []Relabel{{SourceLabel: "status", TargetLabel: "__tmp_status"}},
+	}}}
+	assert.ErrorContains(t, metrics.applyFileSettings(), "metric sessions")
+}
+
+func TestCheckMetricLabelsRelabel(t *testing.T) {
+	e := &Exporter{config: &Config{ConstLabels: map[string]string{"database": "erp"}}}
+	metric := Metric{
+		Labels:       []string{"status"},
+		PerContainer: true,
+		Relabel:      []Relabel{{SourceLabel: "status", Action: "drop", Regex: "INACTIVE"}},
+	}
+	assert.NoError(t, e.checkMetricLabels(metric))
+
+	for _, target := range []string{"database", "con_name"} {
+		metric.Relabel = []Relabel{{SourceLabel: "status", TargetLabel: target, Action: "rename"}}
+		assert.ErrorContains(t, e.checkMetricLabels(metric), "relabel targetlabel "+target)
+	}
+}
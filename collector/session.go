@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// sessionInitConnector runs the session initialization statements on every
+// connection it opens, before the pool hands it out.
+type sessionInitConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c sessionInitConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range c.statements {
+		if err := execStatement(ctx, conn, statement); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("session initialization statement %q failed: %w", statement, err)
+		}
+	}
+	return conn, nil
+}
+
+func execStatement(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		return err
+	}
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	stmtExecer, ok := stmt.(driver.StmtExecContext)
+	if !ok {
+		return errors.New("the driver cannot execute statements")
+	}
+	_, err = stmtExecer.ExecContext(ctx, nil)
+	return err
+}
+
+// openDB opens a connection pool to dsn whose connections run sessionInit
+// once opened.
+func openDB(dsn string, sessionInit []string) (*sql.DB, error) {
+	db, err := sql.Open("oracle", dsn)
+	if err != nil || len(sessionInit) == 0 {
+		return db, err
+	}
+	// sql.Open gives no hook on new connections, rebuild the pool on top of
+	// a connector of the driver
+	driverContext, ok := db.Driver().(driver.DriverContext)
+	db.Close()
+	if !ok {
+		return nil, errors.New("the oracle driver does not support session initialization")
+	}
+	connector, err := driverContext.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(sessionInitConnector{Connector: connector, statements: sessionInit}), nil
+}
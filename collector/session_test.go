@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConn struct {
+	driver.Conn
+	executed []string
+	closed   bool
+	err      error
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.executed = append(c.executed, query)
+	return driver.ResultNoRows, c.err
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+type fakeConnector struct {
+	driver.Connector
+	conn *fakeConn
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.conn, nil
+}
+
+func TestSessionInitConnector(t *testing.T) {
+	statements := []string{"ALTER SESSION SET NLS_NUMERIC_CHARACTERS = '.,'", "ALTER SESSION SET CURRENT_SCHEMA = erp"}
+
+	conn := &fakeConn{}
+	c, err := sessionInitConnector{Connector: fakeConnector{conn: conn}, statements: statements}.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, conn, c)
+	assert.Equal(t, statements, conn.executed)
+
+	conn = &fakeConn{err: errors.New("ORA-00922")}
+	_, err = sessionInitConnector{Connector: fakeConnector{conn: conn}, statements: statements}.Connect(context.Background())
+	assert.ErrorContains(t, err, "ORA-00922")
+	assert.True(t, conn.closed)
+	assert.Len(t, conn.executed, 1)
+}
@@ -0,0 +1,232 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// Targets keeps one Exporter per scraped target so that connection pools are
+// reused between scrapes. Exporters that have not been used for idleTimeout
+// are closed and evicted.
+type Targets struct {
+	mu          sync.Mutex
+	config      *Config
+	idleTimeout time.Duration
+	// maxTargets bounds the number of cached exporters, 0 for no limit
+	maxTargets int
+	// timeoutOffset is subtracted from the scrape timeout of Prometheus
+	timeoutOffset time.Duration
+	exporters     map[string]*targetExporter
+	logger        *zap.SugaredLogger
+}
+
+type targetExporter struct {
+	exporter *Exporter
+	lastUsed time.Time
+}
+
+// NewTargets creates an empty exporter cache. cfg is used as a template for
+// the exporters created by ScrapeHandler and ProbeHandler. Once maxTargets
+// exporters are cached, the least recently used one is closed to make room
+// for a new target.
+func NewTargets(logger *zap.SugaredLogger, cfg *Config, idleTimeout, timeoutOffset time.Duration, maxTargets int) *Targets {
+	return &Targets{
+		config:        cfg,
+		idleTimeout:   idleTimeout,
+		maxTargets:    maxTargets,
+		timeoutOffset: timeoutOffset,
+		exporters:     make(map[string]*targetExporter),
+		logger:        logger,
+	}
+}
+
+// Get returns the exporter cached under key, creating it from cfg if needed.
+func (t *Targets) Get(key string, cfg *Config) (*Exporter, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if te, ok := t.exporters[key]; ok {
+		te.lastUsed = time.Now()
+		return te.exporter, nil
+	}
+	if t.maxTargets > 0 && len(t.exporters) >= t.maxTargets {
+		t.evictLeastRecentlyUsed()
+	}
+	exporter, err := NewExporter(t.logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.Debugw("created exporter for target", "target", maskDsn(cfg.DSN))
+	t.exporters[key] = &targetExporter{exporter: exporter, lastUsed: time.Now()}
+	return exporter, nil
+}
+
+// RunEviction closes the exporters which have been idle for longer than the
+// idle timeout, until ctx is done.
+func (t *Targets) RunEviction(ctx context.Context) {
+	if t.idleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(t.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.evict(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Targets) evict(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, te := range t.exporters {
+		if now.Sub(te.lastUsed) < t.idleTimeout {
+			continue
+		}
+		t.logger.Debugw("evicting idle target", "target", maskDsn(te.exporter.dsn))
+		t.remove(key)
+	}
+}
+
+// evictLeastRecentlyUsed closes the exporter which has not been used for the
+// longest time. t.mu must be held.
+func (t *Targets) evictLeastRecentlyUsed() {
+	var oldestKey string
+	var oldest *targetExporter
+	for key, te := range t.exporters {
+		if oldest == nil || te.lastUsed.Before(oldest.lastUsed) {
+			oldestKey, oldest = key, te
+		}
+	}
+	if oldest == nil {
+		return
+	}
+	t.logger.Debugw("evicting least recently used target", "target", maskDsn(oldest.exporter.dsn))
+	t.remove(oldestKey)
+}
+
+// remove closes and forgets the exporter cached under key. t.mu must be held.
+func (t *Targets) remove(key string) {
+	te := t.exporters[key]
+	if err := te.exporter.Close(); err != nil {
+		t.logger.Errorw("error closing target", "target", maskDsn(te.exporter.dsn), "error", err)
+	}
+	delete(t.exporters, key)
+}
+
+// ScrapeHandler returns the handler of the multi-target endpoint. The target
+// query parameter designates the database to scrape, see TargetDSN.
+func (t *Targets) ScrapeHandler(opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		dsn, err := TargetDSN(t.config.DSN, target, t.config.AllowTargetDSN)
+		if err != nil {
+			t.logger.Debugw("invalid target", "error", err)
+			http.Error(w, "invalid target parameter", http.StatusBadRequest)
+			return
+		}
+		cfg := *t.config
+		cfg.DSN = dsn
+		t.serve(w, r, dsn, &cfg, opts)
+	})
+}
+
+// ProbeHandler returns the handler of the /probe endpoint. Besides the target,
+// the module query parameter selects the settings used to scrape it among
+// modules. Without module parameter, the "default" module is used if it
+// exists, the command line configuration otherwise.
+func (t *Targets) ProbeHandler(modules map[string]Module, opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := r.URL.Query().Get("target")
+		if target == "" {
+			http.Error(w, "target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		moduleName := r.URL.Query().Get("module")
+		module, ok := modules[moduleName]
+		if moduleName == "" {
+			moduleName = "default"
+			module = modules[moduleName]
+		} else if !ok {
+			http.Error(w, fmt.Sprintf("unknown module %q", moduleName), http.StatusBadRequest)
+			return
+		}
+		cfg, err := module.Config(t.config, target)
+		if err != nil {
+			t.logger.Debugw("invalid target", "error", err)
+			http.Error(w, "invalid target parameter", http.StatusBadRequest)
+			return
+		}
+		t.serve(w, r, moduleName+"/"+cfg.DSN, cfg, opts)
+	})
+}
+
+func (t *Targets) serve(w http.ResponseWriter, r *http.Request, key string, cfg *Config, opts promhttp.HandlerOpts) {
+	exporter, err := t.Get(key, cfg)
+	if err != nil {
+		t.logger.Errorw("unable to create exporter", "target", maskDsn(cfg.DSN), "error", err)
+		http.Error(w, "unable to create exporter for target", http.StatusInternalServerError)
+		return
+	}
+	serveMetrics(w, r, nil, []*Exporter{exporter}, t.timeoutOffset, opts)
+}
+
+// TargetDSN builds the DSN used to scrape target. target is either host:port
+// or host:port/service and replaces the address, and the service when given,
+// of the base DSN, keeping its credentials and options. When allowDSN is set,
+// target can also be a connection string (oracle://...), used as is. As the
+// target comes from the HTTP request, it never carries options, some of which
+// make the driver write files.
+func TargetDSN(base, target string, allowDSN bool) (string, error) {
+	if strings.Contains(target, "://") {
+		if !allowDSN {
+			return "", errors.New("connection strings are not allowed as target")
+		}
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return "", errors.New("the connection string of the target must not hold options")
+		}
+		return target, nil
+	}
+	if strings.ContainsAny(target, "?#@ ") {
+		return "", fmt.Errorf("invalid target %q, expected host:port[/service]", target)
+	}
+	if _, _, err := net.SplitHostPort(strings.SplitN(target, "/", 2)[0]); err != nil {
+		return "", fmt.Errorf("invalid target %q, expected host:port[/service]: %w", target, err)
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" {
+		u.Scheme = "oracle"
+	}
+	host, service, found := strings.Cut(target, "/")
+	u.Host = host
+	if found {
+		u.Path = "/" + service
+	}
+	return u.String(), nil
+}
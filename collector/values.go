@@ -0,0 +1,210 @@
+package collector
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// secondsPerMonth is the average length of a month of the Gregorian
+// calendar, used to convert INTERVAL YEAR TO MONTH values to seconds.
+const secondsPerMonth = 2629746
+
+var (
+	intervalDSPattern = regexp.MustCompile(`^([+-])?(\d+) (\d+):(\d+):(\d+(?:\.\d*)?)$`)
+	intervalYMPattern = regexp.MustCompile(`^([+-])?(\d+)-(\d+)$`)
+)
+
+// NULL value policies of the nullvalue and nullvalues metric settings.
+const (
+	nullSkip = "skip"
+	nullZero = "zero"
+	nullNaN  = "nan"
+)
+
+// row holds the values of a result row by lower-cased column name.
+type row map[string]columnValue
+
+// columnValue is the value of a column converted according to its Oracle
+// type. Numbers, dates and intervals carry a numeric value, dates being
+// converted to unix seconds and intervals to seconds.
+type columnValue struct {
+	text    string
+	value   float64
+	numeric bool
+	null    bool
+}
+
+func (v columnValue) String() string {
+	return v.text
+}
+
+// float returns the numeric value of the column, or an error when it does not
+// hold a number.
+func (v columnValue) float() (float64, error) {
+	if v.null {
+		return 0, fmt.Errorf("NULL value")
+	}
+	if !v.numeric {
+		return 0, fmt.Errorf("%q is not a number", v.text)
+	}
+	return v.value, nil
+}
+
+// mapValue returns the value of the column translated by valueMap. An exact
+// match of the text of the column wins, numbers are kept as is, and the "*"
+// entry is used for any other value.
+func (v columnValue) mapValue(valueMap map[string]float64) columnValue {
+	if len(valueMap) == 0 || v.null {
+		return v
+	}
+	if value, ok := valueMap[strings.TrimSpace(v.text)]; ok {
+		return columnValue{text: v.text, value: value, numeric: true}
+	}
+	if v.numeric {
+		return v
+	}
+	if value, ok := valueMap["*"]; ok {
+		return columnValue{text: v.text, value: value, numeric: true}
+	}
+	return v
+}
+
+// count returns the value of the column as a sample count.
+func (v columnValue) count() (uint64, error) {
+	value, err := v.float()
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value != math.Trunc(value) {
+		return 0, fmt.Errorf("%v is not a count", value)
+	}
+	return uint64(value), nil
+}
+
+// newColumnValue converts the value scanned from a column whose database type
+// name is databaseType, as given by the go-ora driver.
+func newColumnValue(value interface{}, databaseType string) columnValue {
+	switch v := value.(type) {
+	case nil:
+		return columnValue{null: true}
+	case time.Time:
+		return columnValue{text: fmt.Sprint(v), value: float64(v.UnixNano()) / 1e9, numeric: true}
+	case float64:
+		return columnValue{text: fmt.Sprint(v), value: v, numeric: true}
+	case float32:
+		return columnValue{text: fmt.Sprint(v), value: float64(v), numeric: true}
+	case int64:
+		return columnValue{text: fmt.Sprint(v), value: float64(v), numeric: true}
+	case uint64:
+		return columnValue{text: fmt.Sprint(v), value: float64(v), numeric: true}
+	case bool:
+		if v {
+			return columnValue{text: "true", value: 1, numeric: true}
+		}
+		return columnValue{text: "false", numeric: true}
+	case []byte:
+		return parseColumnValue(string(v), databaseType)
+	case string:
+		return parseColumnValue(v, databaseType)
+	default:
+		return columnValue{text: fmt.Sprint(v)}
+	}
+}
+
+// parseColumnValue converts the text returned for NUMBER and interval
+// columns. Character columns are numeric when they hold a number or an
+// interval.
+func parseColumnValue(text, databaseType string) columnValue {
+	cv := columnValue{text: text}
+	trimmed := strings.TrimSpace(text)
+	var err error
+	switch strings.ToUpper(databaseType) {
+	case "INTERVALDS_DTY", "INTERVAL DAY TO SECOND":
+		cv.value, err = parseIntervalDS(trimmed)
+	case "INTERVALYM_DTY", "INTERVAL YEAR TO MONTH":
+		cv.value, err = parseIntervalYM(trimmed)
+	default:
+		cv.value, err = strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			cv.value, err = parseIntervalDS(trimmed)
+		}
+	}
+	cv.numeric = err == nil
+	return cv
+}
+
+// parseIntervalDS converts an INTERVAL DAY TO SECOND value such as
+// "+01 02:03:04.500000" to seconds.
+func parseIntervalDS(interval string) (float64, error) {
+	matches := intervalDSPattern.FindStringSubmatch(interval)
+	if matches == nil {
+		return 0, fmt.Errorf("%q is not an interval day to second", interval)
+	}
+	days, _ := strconv.ParseFloat(matches[2], 64)
+	hours, _ := strconv.ParseFloat(matches[3], 64)
+	minutes, _ := strconv.ParseFloat(matches[4], 64)
+	seconds, _ := strconv.ParseFloat(matches[5], 64)
+	value := days*86400 + hours*3600 + minutes*60 + seconds
+	if matches[1] == "-" {
+		value = -value
+	}
+	return value, nil
+}
+
+// parseIntervalYM converts an INTERVAL YEAR TO MONTH value such as "+01-06"
+// to seconds.
+func parseIntervalYM(interval string) (float64, error) {
+	matches := intervalYMPattern.FindStringSubmatch(interval)
+	if matches == nil {
+		return 0, fmt.Errorf("%q is not an interval year to month", interval)
+	}
+	years, _ := strconv.ParseFloat(matches[2], 64)
+	months, _ := strconv.ParseFloat(matches[3], 64)
+	value := (years*12 + months) * secondsPerMonth
+	if matches[1] == "-" {
+		value = -value
+	}
+	return value, nil
+}
+
+// nullPolicy returns the policy applied to the NULL values of column: the one
+// of nullvalues for this column, the nullvalue of the metric otherwise, and
+// skip by default.
+func nullPolicy(metric Metric, column string) (string, error) {
+	policy := metric.NullValue
+	for c, p := range metric.NullValues {
+		if strings.EqualFold(c, column) {
+			policy = p
+			break
+		}
+	}
+	switch policy = strings.ToLower(policy); policy {
+	case "":
+		return nullSkip, nil
+	case nullSkip, nullZero, nullNaN:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid NULL value policy %q of column %s, expected skip, zero or nan", policy, column)
+	}
+}
+
+// nullValue returns the value sent for a NULL value of column, ok being false
+// when the sample is skipped.
+func nullValue(metric Metric, column string) (value float64, ok bool, err error) {
+	policy, err := nullPolicy(metric, column)
+	if err != nil {
+		return 0, false, err
+	}
+	switch policy {
+	case nullZero:
+		return 0, true, nil
+	case nullNaN:
+		return math.NaN(), true, nil
+	default:
+		return 0, false, nil
+	}
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewColumnValue(t *testing.T) {
+	date := time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	tests := []struct {
+		value        interface{}
+		databaseType string
+		expected     float64
+	}{
+		{"42", "NUMBER", 42},
+		{"-0.25", "NUMBER", -0.25},
+		{float64(1.5), "IBDouble", 1.5},
+		{float32(2), "IBFloat", 2},
+		{date, "DATE", float64(date.Unix()) + 0.5},
+		{"+01 02:03:04.250000", "IntervalDS_DTY", 93784.25},
+		{"-00 00:01:30.000000", "IntervalDS_DTY", -90},
+		{"+01-06", "IntervalYM_DTY", 18 * secondsPerMonth},
+		{" 12 ", "VARCHAR2", 12},
+		{"+00 00:00:10", "CHAR", 10},
+	}
+	for _, test := range tests {
+		value, err := newColumnValue(test.value, test.databaseType).float()
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, value, test.value)
+	}
+
+	_, err := newColumnValue("UNLIMITED", "VARCHAR2").float()
+	assert.Error(t, err)
+	assert.Equal(t, "UNLIMITED", newColumnValue("UNLIMITED", "VARCHAR2").String())
+
+	null := newColumnValue(nil, "NUMBER")
+	assert.Equal(t, "", null.String())
+	_, err = null.float()
+	assert.Error(t, err)
+
+	count, err := newColumnValue("12", "NUMBER").count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), count)
+	_, err = newColumnValue("1.5", "NUMBER").count()
+	assert.Error(t, err)
+}
+
+func TestNullValue(t *testing.T) {
+	metric := Metric{NullValue: "NaN", NullValues: map[string]string{"SIZE_MB": "zero", "files": "skip"}}
+
+	value, ok, err := nullValue(metric, "size_mb")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, value)
+
+	_, ok, err = nullValue(metric, "files")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	value, ok, err = nullValue(metric, "used")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, math.IsNaN(value))
+
+	_, ok, err = nullValue(Metric{}, "used")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = nullPolicy(Metric{NullValue: "empty"}, "used")
+	assert.Error(t, err)
+}
+
+func TestMapValue(t *testing.T) {
+	valueMap := map[string]float64{"OPEN": 1, "MOUNTED": 2, "-1": 0, "*": -1}
+	tests := []struct {
+		value    interface{}
+		expected float64
+	}{
+		{"OPEN", 1},
+		{"MOUNTED  ", 2},
+		{"-1", 0},
+		{"12", 12},
+		{"STARTED", -1},
+	}
+	for _, test := range tests {
+		value, err := newColumnValue(test.value, "CHAR").mapValue(valueMap).float()
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, value, test.value)
+	}
+
+	_, err := newColumnValue("STARTED", "CHAR").mapValue(map[string]float64{"OPEN": 1}).float()
+	assert.Error(t, err)
+	assert.True(t, newColumnValue(nil, "CHAR").mapValue(valueMap).null)
+}
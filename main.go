@@ -59,6 +59,42 @@ var (
 		"scrape.interval",
 		"Interval between each scrape. Default is to scrape on collect requests",
 	).Default("0s").Duration()
+	scrapeWorkers = kingpin.Flag(
+		"scrape.workers",
+		"Maximum number of metric requests running at the same time during a scrape, 0 for no limit. (env: SCRAPE_WORKERS)",
+	).Default(getEnv("SCRAPE_WORKERS", "0")).Int()
+	scrapeTimeoutOffset = kingpin.Flag(
+		"scrape.timeout-offset",
+		"Offset to subtract from the timeout of Prometheus scrapes, so that queries are cancelled before Prometheus gives up. (env: SCRAPE_TIMEOUT_OFFSET)",
+	).Default(getEnv("SCRAPE_TIMEOUT_OFFSET", "500ms")).Duration()
+	targetIdleTimeout = kingpin.Flag(
+		"scrape.target-idle-timeout",
+		"Time after which the connection pool of an unused /scrape target is closed. (env: SCRAPE_TARGET_IDLE_TIMEOUT)",
+	).Default(getEnv("SCRAPE_TARGET_IDLE_TIMEOUT", "5m")).Duration()
+	targetMaxTargets = kingpin.Flag(
+		"scrape.max-targets",
+		"Maximum number of /scrape and /probe targets whose connection pool is kept open, 0 for no limit. (env: SCRAPE_MAX_TARGETS)",
+	).Default(getEnv("SCRAPE_MAX_TARGETS", "100")).Int()
+	allowTargetDSN = kingpin.Flag(
+		"scrape.allow-target-dsn",
+		"Accept connection strings (oracle://...) as /scrape and /probe targets, rather than host:port[/service] only. (env: SCRAPE_ALLOW_TARGET_DSN)",
+	).Default(getEnv("SCRAPE_ALLOW_TARGET_DSN", "false")).Bool()
+	configFile = kingpin.Flag(
+		"config.file",
+		"File with the exporter configuration (databases, probe modules) in a toml or yaml format. (env: CONFIG_FILE)",
+	).Default(getEnv("CONFIG_FILE", "")).String()
+	constLabels = kingpin.Flag(
+		"metrics.constlabels",
+		"Constant labels added to every series, as a comma separated list of name=value pairs. (env: METRICS_CONSTLABELS)",
+	).Default(getEnv("METRICS_CONSTLABELS", "")).String()
+	identityLabels = kingpin.Flag(
+		"metrics.identity-labels",
+		"Add the dbid and db_unique_name labels of the database to the series of the metrics. (env: METRICS_IDENTITY_LABELS)",
+	).Default(getEnv("METRICS_IDENTITY_LABELS", "false")).Bool()
+	metricsNamespace = kingpin.Flag(
+		"metrics.namespace",
+		"Namespace of the metrics, oracledb by default. (env: METRICS_NAMESPACE)",
+	).Default(getEnv("METRICS_NAMESPACE", "")).String()
 	toolkitFlags = webflag.AddFlags(kingpin.CommandLine, ":9161")
 )
 
@@ -86,6 +122,28 @@ func main() {
 	}
 	//EOF
 
+	fileConfig := &collector.FileConfig{}
+	if *configFile != "" {
+		var err error
+		fileConfig, err = collector.LoadFileConfig(*configFile)
+		if err != nil {
+			logger.Errorw("Unable to load exporter configuration", "file", *configFile, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Labels of the command line override the ones of the configuration file
+	labels, err := collector.ParseConstLabels(*constLabels)
+	if err != nil {
+		logger.Errorw("Invalid constant labels", "error", err)
+		os.Exit(1)
+	}
+	for name, value := range fileConfig.ConstLabels {
+		if _, ok := labels[name]; !ok {
+			labels[name] = value
+		}
+	}
+
 	config := &collector.Config{
 		DSN:                *dsn,
 		MaxOpenConns:       *maxOpenConns,
@@ -93,19 +151,53 @@ func main() {
 		CustomMetrics:      *customMetrics,
 		QueryTimeout:       *queryTimeout,
 		DefaultMetricsFile: *defaultFileMetrics,
+		ScrapeWorkers:      *scrapeWorkers,
+		SessionInit:        fileConfig.SessionInit,
+		ConstLabels:        labels,
+		IdentityLabels:     *identityLabels || fileConfig.IdentityLabels,
+		AllowTargetDSN:     *allowTargetDSN,
+		Namespace:          *metricsNamespace,
 	}
-	exporter, err := collector.NewExporter(logger, config)
-	if err != nil {
-		logger.Errorw("unable to connect to DB", err)
+	if config.Namespace == "" {
+		config.Namespace = fileConfig.Namespace
+	}
+	if config.Namespace != "" {
+		if err := collector.ValidateNamespace(config.Namespace); err != nil {
+			logger.Errorw("Invalid metrics namespace", "error", err)
+			os.Exit(1)
+		}
+	}
+	var exporters []*collector.Exporter
+	// The database given on the command line is optional when databases are
+	// listed in the configuration file
+	if *dsn != "" || len(fileConfig.Databases) == 0 {
+		exporter, err := collector.NewExporter(logger, config)
+		if err != nil {
+			logger.Errorw("unable to connect to DB", err)
+		}
+		exporters = append(exporters, exporter)
+	}
+	for name, database := range fileConfig.Databases {
+		databaseConfig, err := database.Config(name, config)
+		if err != nil {
+			logger.Errorw("Invalid database configuration", "database", name, "error", err)
+			os.Exit(1)
+		}
+		exporter, err := collector.NewExporter(logger, databaseConfig)
+		if err != nil {
+			logger.Errorw("unable to connect to DB", "database", name, "error", err)
+		}
+		exporters = append(exporters, exporter)
 	}
 
 	if *scrapeInterval != 0 {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
-		go exporter.RunScheduledScrapes(ctx, *scrapeInterval)
+		for _, exporter := range exporters {
+			go exporter.RunScheduledScrapes(ctx, *scrapeInterval)
+		}
 	}
 
-	prometheus.MustRegister(exporter)
 	prometheus.MustRegister(collectors.NewBuildInfoCollector())
 
 	logger.Infow("Starting oracledb_exporter", "version", version.Info())
@@ -115,7 +207,14 @@ func main() {
 	opts := promhttp.HandlerOpts{
 		ErrorHandling: promhttp.ContinueOnError,
 	}
-	http.Handle(*metricPath, promhttp.HandlerFor(prometheus.DefaultGatherer, opts))
+	http.Handle(*metricPath, collector.MetricsHandler(prometheus.DefaultGatherer, exporters, *scrapeTimeoutOffset, opts))
+
+	targets := collector.NewTargets(logger, config, *targetIdleTimeout, *scrapeTimeoutOffset, *targetMaxTargets)
+	evictionCtx, stopEviction := context.WithCancel(context.Background())
+	defer stopEviction()
+	go targets.RunEviction(evictionCtx)
+	http.Handle("/scrape", targets.ScrapeHandler(opts))
+	http.Handle("/probe", targets.ProbeHandler(fileConfig.Modules, opts))
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("<html><head><title>Oracle DB Exporter " + Version + "</title></head><body><h1>Oracle DB Exporter " + Version + "</h1><p><a href='" + *metricPath + "'>Metrics</a></p></body></html>"))
 	})